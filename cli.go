@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -161,8 +162,45 @@ func (s *boolSlice) Set(value string) error {
 	}
 
 	for _, potentialBool := range strings.Split(value, ",") {
-		*s.target = append(*s.target, potentialBool == "true")
+		b, err := parseBool(potentialBool)
+		if err != nil {
+			return err
+		}
+		*s.target = append(*s.target, b)
+	}
+	return nil
+}
+
+// negatedBool is the --no-<flag> counterpart of a bool flag, setting it stores the inverse in target
+type negatedBool struct {
+	target *bool
+	value  bool
+}
+
+func (n *negatedBool) IsBoolFlag() bool {
+	return true
+}
+
+func (n *negatedBool) String() string {
+	if n == nil {
+		return "false"
+	}
+
+	return strconv.FormatBool(n.value)
+}
+
+func (n *negatedBool) Set(value string) error {
+	if n == nil || n.target == nil {
+		return errors.New("nil")
+	}
+
+	b, err := parseBool(value)
+	if err != nil {
+		return err
 	}
+
+	n.value = b
+	*n.target = !b
 	return nil
 }
 
@@ -201,6 +239,132 @@ func (s *TextSlice) Set(value string) error {
 
 type ciParser[T any] struct {
 	o *options
+
+	parsed   bool
+	parseErr error
+
+	// flags are parsed in to a copy, and only the values that were set are copied to the result when applied
+	dummy *T
+	set   []cliFlagSet
+
+	// positional arguments that were not bound to a field
+	remaining []string
+}
+
+type cliAssociation struct {
+	v    reflect.Value
+	path []string
+	tag  reflect.StructTag
+
+	// name is the primary flag name, which differs from the flag that was set when an alias is used
+	name       string
+	alias      bool
+	deprecated bool
+}
+
+type cliFlagSet struct {
+	cliAssociation
+	flag  string
+	value string
+}
+
+// cliLevel holds the flags for the top level, or a single selected command
+type cliLevel struct {
+	fs *flag.FlagSet
+	// set when GNU style flags are enabled
+	gnu *gnuParser
+
+	command      *command
+	associations map[string]cliAssociation
+
+	// fields populated from positional arguments rather than flags
+	positional []positionalField
+
+	// the fields that have flags, in struct order, used for the help output
+	options []levelOption
+}
+
+type levelOption struct {
+	field fieldsData
+	name  string
+}
+
+// boundFlag wraps the value of a flag registered with RegisterFlags, the flag set is parsed by the caller (or another flag library)
+// so confy records whether the flag was set rather than relying on flag.FlagSet.Visit
+type boundFlag struct {
+	flag.Value
+	association cliAssociation
+
+	// name the flag was registered under, which is the alias or short name rather than association.name when the flag is one
+	name string
+
+	// short single letter name for libraries that support them (e.g pflag)
+	short    string
+	typeName string
+	defValue string
+
+	set bool
+}
+
+func (b *boundFlag) String() string {
+	if b == nil || b.Value == nil {
+		return ""
+	}
+
+	return b.Value.String()
+}
+
+func (b *boundFlag) Set(value string) error {
+	if err := b.Value.Set(value); err != nil {
+		return err
+	}
+
+	b.set = true
+	return nil
+}
+
+func (b *boundFlag) IsBoolFlag() bool {
+	bf, ok := b.Value.(boolFlag)
+	return ok && bf.IsBoolFlag()
+}
+
+// Type returns the name of the flags value type, this satisfies the pflag.Value interface
+func (b *boundFlag) Type() string {
+	return b.typeName
+}
+
+// Shorthand returns the short name of the flag, empty if it has none
+func (b *boundFlag) Shorthand() string {
+	return b.short
+}
+
+// Reset marks the flag as not set and restores its default value, so that the flag set can be parsed again (e.g by a cobra command that is executed more than once)
+func (b *boundFlag) Reset() error {
+	b.set = false
+
+	if b.association.v.IsValid() {
+		b.association.v.SetZero()
+		return nil
+	}
+
+	return b.Value.Set(b.defValue)
+}
+
+// boundFlags returns the flags within fs that were registered by RegisterFlags
+func boundFlags(fs *flag.FlagSet) (bound []*boundFlag) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if b, ok := f.Value.(*boundFlag); ok {
+			bound = append(bound, b)
+		}
+	})
+
+	return bound
+}
+
+type positionalField struct {
+	field fieldsData
+	// index of the argument, or -1 for confy_args fields that take all remaining arguments
+	index int
 }
 
 func newCliLoader[T any](o *options) *ciParser[T] {
@@ -216,14 +380,44 @@ func GetGeneratedCliFlags[T any](delimiter string) []string {
 		panic("GetGeneratedEnv(...) only supports configs of Struct type")
 	}
 
-	o := options{}
-	FromCli(delimiter)(&o)
+	// FromCli cannot fail, so neither can this
+	result, _ := GetGeneratedCliFlagsWithOptions[T](FromCli(delimiter))
+	return result
+}
+
+// GetGeneratedCliFlagsWithOptions return list of auto generated cli flag names that Config will check when given the same options
+// this takes into account the delimiter, naming, GNU style and transform that the options set. Flags of commands are named with their full path
+func GetGeneratedCliFlagsWithOptions[T any](suppliedOptions ...OptionFunc) ([]string, error) {
+	var a T
+	if reflect.TypeOf(a).Kind() != reflect.Struct {
+		panic("GetGeneratedCliFlagsWithOptions(...) only supports configs of Struct type")
+	}
+
+	o := options{
+		currentlySet: make(map[preference]bool),
+	}
+
+	var errs []error
+	for _, optFunc := range suppliedOptions {
+		if err := optFunc(&o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if o.cli.delimiter == "" {
+		o.cli.delimiter = CLIDelimiter
+	}
+
 	cp := newCliLoader[T](&o)
 
 	var result []string
 	for _, field := range getFields(true, &a) {
 
-		cliName, ok := determineVariableName(&a, cp.o.cli.delimiter, nil, field)
+		cliName, ok := determineVariableName(&a, cp.naming(), field)
 		if !ok {
 			continue
 		}
@@ -231,7 +425,42 @@ func GetGeneratedCliFlags[T any](delimiter string) []string {
 		result = append(result, cliName)
 	}
 
-	return result
+	return result, nil
+}
+
+// RegisterFlags registers the cli flags confy generates for T on fs without parsing it, for when fs is parsed by the caller or another flag library
+// Once fs has been parsed, pass it to Config with WithFlagSet(fs) and the same options, confy then uses the flags that were set instead of parsing the arguments itself
+// Only the top level flags are registered, commands and positional arguments need confy to parse the arguments
+func RegisterFlags[T any](fs *flag.FlagSet, suppliedOptions ...OptionFunc) error {
+	var a T
+	if reflect.TypeOf(a).Kind() != reflect.Struct {
+		panic("RegisterFlags(...) only supports configs of Struct type")
+	}
+
+	if fs == nil {
+		return errors.New("flag set must not be nil")
+	}
+
+	o := options{
+		currentlySet: make(map[preference]bool),
+	}
+
+	var errs []error
+	for _, optFunc := range suppliedOptions {
+		if err := optFunc(&o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if o.cli.delimiter == "" {
+		o.cli.delimiter = CLIDelimiter
+	}
+
+	return newCliLoader[T](&o).register(fs)
 }
 
 // GetGeneratedCliFlagsWithTransform return list of auto generated cli flag names that LoadEnv/Config will check
@@ -274,54 +503,167 @@ func LoadCliWithTransform[T any](delimiter string, transform func(string) string
 	return
 }
 
-func (cp *ciParser[T]) usage(f *flag.FlagSet) func() {
+func (cp *ciParser[T]) usage(level *cliLevel) func() {
 
 	return func() {
-		fmt.Fprintf(f.Output(), "Structure options: \n")
-		f.PrintDefaults()
+		if err := cp.o.helpTemplate().Execute(level.fs.Output(), cp.helpData(level)); err != nil {
+			logger.Error("failed to execute help template", "err", err)
+			level.fs.PrintDefaults()
+		}
 	}
 }
 
-func (cp *ciParser[T]) apply(result *T) (somethingSet bool, err error) {
+// registerFlag adds value to the flag set under name, using the flag type that matches the kind of value
+// returns false if the type is not supported
+func (cp *ciParser[T]) registerFlag(fs *flag.FlagSet, name, description string, value reflect.Value, path []string) bool {
+	switch value.Kind() {
+	case reflect.String:
+		fs.StringVar(value.Addr().Interface().(*string), name, "", description)
+	case reflect.Int:
+		fs.IntVar(value.Addr().Interface().(*int), name, 0, description)
+	case reflect.Int64:
+		fs.Int64Var(value.Addr().Interface().(*int64), name, 0, description)
+	case reflect.Bool:
+		fs.BoolVar(value.Addr().Interface().(*bool), name, false, description)
+	case reflect.Float64:
+		fs.Float64Var(value.Addr().Interface().(*float64), name, 0, description)
+	case reflect.Slice:
+		var parser flag.Value
+		sliceContentType := value.Type().Elem()
+		switch sliceContentType.Kind() {
+		case reflect.String:
+			parser = newStringSlice(value.Addr().Interface())
+		case reflect.Int, reflect.Int64:
+			parser = newIntSlice(value.Addr().Interface())
+		case reflect.Float64:
+			parser = newFloatSlice(value.Addr().Interface())
+		case reflect.Bool:
+			parser = newBoolSlice(value.Addr().Interface())
+		default:
+			inter := reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+			if !reflect.PointerTo(sliceContentType).Implements(inter) {
+				logger.Warn("type inside of complex slice did not implement encoding.TextUnmarshaler", "flag", name, "path", strings.Join(path, cp.o.cli.delimiter))
+				return false
+			}
+			parser = newGenericSlice(sliceContentType)
+		}
+
+		fs.Var(parser, name, description)
+	case reflect.Struct:
+
+		textUnmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler)
+		if !ok {
+			logger.Warn("structure doesnt implement encoding.TextUnmarshaler", "flag", name, "path", strings.Join(path, cp.o.cli.delimiter))
+			return false
+		}
 
-	if len(os.Args) == 0 {
-		logger.Info("no os arguments supplied, not trying to parse cli")
-		return false, nil
+		textMarshaler, ok := value.Addr().Interface().(encoding.TextMarshaler)
+		if !ok {
+			logger.Warn("structure doesnt implement encoding.TextMarshaler", "flag", name, "path", strings.Join(path, cp.o.cli.delimiter))
+			return false
+		}
+
+		fs.TextVar(textUnmarshaler, name, textMarshaler, description)
+	default:
+		logger.Warn("unsupported type for cli auto-addition", "type", value.Kind().String(), "path", strings.Join(path, cp.o.cli.delimiter))
+		return false
 	}
 
-	cp.o.cli.commandLine.SetOutput(os.Stdout)
-	cp.o.cli.commandLine.Usage = cp.usage(cp.o.cli.commandLine)
-	if len(os.Args) <= 1 {
-		logger.Info("one os arguments supplied, not trying to parse cli")
-		// There were no args to parse, so the user must not be using the cli
-		return false, nil
+	return true
+}
+
+func (cp *ciParser[T]) newLevel(fs *flag.FlagSet, cmd *command) *cliLevel {
+	level := &cliLevel{
+		fs:           fs,
+		command:      cmd,
+		associations: map[string]cliAssociation{},
 	}
 
-	// stop go flag from overwritting literally all configuration data on default write
-	dummyCopy := new(T)
+	if cp.o.cli.gnu {
+		level.gnu = newGnuParser(fs)
+		// stop at the first positional argument so that it can be checked for being a command
+		level.gnu.stopAtPositional = len(cp.o.commands.children(cmd)) > 0
+	}
+
+	// a flag set supplied with WithFlagSet keeps its own output and usage
+	if cmd != nil || !cp.o.cli.external {
+		fs.SetOutput(os.Stdout)
+		fs.Usage = cp.usage(level)
+	}
+
+	return level
+}
 
-	type association struct {
-		v    reflect.Value
-		path []string
-		tag  reflect.StructTag
+// naming returns the naming options for flags, GNU style flags default to kebab case
+func (cp *ciParser[T]) naming() envOptions {
+	naming := cp.o.cli.envOptions
+	if cp.o.cli.gnu && naming.naming == AsIs {
+		naming.naming = KebabCase
 	}
 
-	flagAssociation := map[string]association{}
+	return naming
+}
+
+const sourceHelpFlag = "struct-help"
 
-	const sourceHelpFlag = "struct-help"
-	cp.o.cli.commandLine.Bool(sourceHelpFlag, true, "Print command line flags generated by confy")
-	for _, field := range getFields(true, dummyCopy) {
+// registerLevel adds the flags for the fields that belong to the levels command (or the top level) to its flag set
+func (cp *ciParser[T]) registerLevel(result *T, level *cliLevel) error {
+
+	naming := cp.naming()
+
+	// flags for a command are named relative to the command, e.g "serve -port" rather than "-serve.port"
+	depth := 0
+	if level.command != nil {
+		depth = len(level.command.path)
+	}
+
+	fs := level.fs
+	fs.Bool(sourceHelpFlag, true, "Print command line flags generated by confy")
+
+	if level.command == nil && cp.o.config.pathFlag != "" && fs.Lookup(cp.o.config.pathFlag) == nil {
+		// registered so that the cli source does not complain about an undefined flag, the value is read by the config file source
+		fs.String(cp.o.config.pathFlag, cp.o.config.pathFlagDefault, cp.o.config.pathFlagDescription)
+	}
+
+	if level.command == nil && cp.o.cli.printFlag != "" && fs.Lookup(cp.o.cli.printFlag) == nil {
+		fs.String(cp.o.cli.printFlag, "", "Print the merged configuration as json, yaml or toml and exit")
+	}
+	for _, field := range getFields(true, cp.dummy) {
 
 		willAccess := field.value.CanAddr() && field.value.CanInterface()
 		logger.Info("got field from config", slog.Any(strings.Join(field.path, "."), field.value.String()), "will_continue_parsing", fmt.Sprintf("%t (addr: %t, intf: %t)", willAccess, field.value.CanAddr(), field.value.CanInterface()))
 
+		owner := cp.o.commands.owner(field.path)
+		if (owner == nil) != (level.command == nil) || (owner != nil && !slices.Equal(owner.path, level.command.path)) || cp.o.commands.isCommand(field.path) {
+			continue
+		}
+
 		if willAccess {
 
 			if field.value.Kind() == reflect.Ptr {
 				field.value = field.value.Elem()
 			}
 
-			flagName, ok := determineVariableName(result, cp.o.cli.delimiter, cp.o.cli.transform, field)
+			if arg, ok := field.tag.Lookup(confyArgTag); ok {
+				index, err := strconv.Atoi(arg)
+				if err != nil || index < 0 {
+					return fmt.Errorf("%w: invalid %s:%q on %s, must be a positive index", errFatal, confyArgTag, arg, strings.Join(field.path, "."))
+				}
+
+				level.positional = append(level.positional, positionalField{field: field, index: index})
+				continue
+			}
+
+			if _, ok := field.tag.Lookup(confyArgsTag); ok {
+				if field.value.Kind() != reflect.Slice {
+					return fmt.Errorf("%w: %s field %s must be a slice", errFatal, confyArgsTag, strings.Join(field.path, "."))
+				}
+
+				level.positional = append(level.positional, positionalField{field: field, index: -1})
+				continue
+			}
+
+			flagName, ok := determineRelativeVariableName(result, naming, field, depth)
 			if !ok {
 				// logging done in determine variable
 				continue
@@ -348,70 +690,69 @@ func (cp *ciParser[T]) apply(result *T) (somethingSet bool, err error) {
 			}
 
 			logger.Info("adding flag", "flag", "-"+flagName, "type", field.value.Kind())
-			flagAssociation[flagName] = association{v: field.value, path: field.path, tag: field.tag}
-
-			switch field.value.Kind() {
-			case reflect.String:
-				cp.o.cli.commandLine.StringVar(field.value.Addr().Interface().(*string), flagName, "", description)
-			case reflect.Int:
-				cp.o.cli.commandLine.IntVar(field.value.Addr().Interface().(*int), flagName, 0, description)
-			case reflect.Int64:
-				cp.o.cli.commandLine.Int64Var(field.value.Addr().Interface().(*int64), flagName, 0, description)
-			case reflect.Bool:
-				cp.o.cli.commandLine.BoolVar(field.value.Addr().Interface().(*bool), flagName, false, description)
-			case reflect.Float64:
-				cp.o.cli.commandLine.Float64Var(field.value.Addr().Interface().(*float64), flagName, 0, description)
-			case reflect.Slice:
-				var parser flag.Value
-				sliceContentType := field.value.Type().Elem()
-				switch sliceContentType.Kind() {
-				case reflect.String:
-					parser = newStringSlice(field.value.Addr().Interface())
-				case reflect.Int, reflect.Int64:
-					parser = newIntSlice(field.value.Addr().Interface())
-				case reflect.Float64:
-					parser = newFloatSlice(field.value.Addr().Interface())
-				case reflect.Bool:
-					parser = newBoolSlice(field.value.Addr().Interface())
-				default:
-					inter := reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
-					if !reflect.PointerTo(sliceContentType).Implements(inter) {
-						logger.Warn("type inside of complex slice did not implement encoding.TextUnmarshaler", "flag", flagName, "path", strings.Join(field.path, cp.o.cli.delimiter))
-						continue
+			if !cp.registerFlag(fs, flagName, description, field.value, field.path) {
+				continue
+			}
+			level.associations[flagName] = cliAssociation{v: field.value, path: field.path, tag: field.tag, name: flagName}
+			level.options = append(level.options, levelOption{field: field, name: flagName})
+
+			if short, ok := field.tag.Lookup(confyShortTag); ok {
+				if level.gnu != nil {
+					if err := level.gnu.addShort(short, flagName); err != nil {
+						return fmt.Errorf("%w: %s", errFatal, err)
+					}
+				} else {
+					if fs.Lookup(short) != nil {
+						return fmt.Errorf("%w: short flag -%s for -%s is already defined", errFatal, short, flagName)
 					}
-					parser = newGenericSlice(sliceContentType)
+
+					cp.registerFlag(fs, short, fmt.Sprintf("Short for -%s", flagName), field.value, field.path)
+					level.associations[short] = cliAssociation{v: field.value, path: field.path, tag: field.tag, name: flagName}
 				}
+			}
 
-				cp.o.cli.commandLine.Var(parser, flagName, description)
-			case reflect.Struct:
+			if field.value.Kind() == reflect.Bool && !hasModifier(field.tag, confyTag, "nonegate") {
+				negatedName := "no-" + flagName
+				if fs.Lookup(negatedName) != nil {
+					return fmt.Errorf("%w: negated flag -%s for -%s is already defined, use the nonegate modifier", errFatal, negatedName, flagName)
+				}
+
+				logger.Info("adding negated flag", "flag", "-"+negatedName)
+				fs.Var(&negatedBool{target: field.value.Addr().Interface().(*bool)}, negatedName, fmt.Sprintf("Set -%s to false", flagName))
+				level.associations[negatedName] = cliAssociation{v: field.value, path: field.path, tag: field.tag, name: flagName}
+			}
 
-				textUnmarshaler, ok := field.value.Addr().Interface().(encoding.TextUnmarshaler)
-				if !ok {
-					logger.Warn("structure doesnt implement encoding.TextUnmarshaler", "flag", flagName, "path", strings.Join(field.path, cp.o.cli.delimiter))
-					continue
+			aliases, deprecated := determineAliasNames(result, naming, field, depth)
+			for _, alias := range aliases {
+				aliasDescription := fmt.Sprintf("Alias of -%s", flagName)
+				if deprecated {
+					aliasDescription = fmt.Sprintf("Deprecated, use -%s instead", flagName)
 				}
 
-				textMarshaler, ok := field.value.Addr().Interface().(encoding.TextMarshaler)
-				if !ok {
-					logger.Warn("structure doesnt implement encoding.TextMarshaler", "flag", flagName, "path", strings.Join(field.path, cp.o.cli.delimiter))
-					continue
+				if fs.Lookup(alias) != nil {
+					return fmt.Errorf("%w: alias -%s of -%s is already defined", errFatal, alias, flagName)
 				}
 
-				cp.o.cli.commandLine.TextVar(textUnmarshaler, flagName, textMarshaler, description)
-			default:
-				logger.Warn("unsupported type for cli auto-addition", "type", field.value.Kind().String(), "path", strings.Join(field.path, cp.o.cli.delimiter))
-				continue
+				logger.Info("adding alias flag", "flag", "-"+alias, "alias_of", flagName)
+				cp.registerFlag(fs, alias, aliasDescription, field.value, field.path)
+				level.associations[alias] = cliAssociation{v: field.value, path: field.path, tag: field.tag, name: flagName, alias: true, deprecated: deprecated}
 			}
 		}
 
 	}
-	err = cp.o.cli.commandLine.Parse(os.Args[1:])
-	if err != nil {
-		return false, err
-	}
 
-	help := false
-	cp.o.cli.commandLine.Visit(func(f *flag.Flag) {
+	return nil
+}
+
+// collect records which of the levels flags were set
+func (cp *ciParser[T]) collect(level *cliLevel) (help bool, err error) {
+
+	setBy := map[string]setFlag{}
+	level.fs.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+
 		if f.Name == sourceHelpFlag {
 			logger.Info("the help flag was set", "flag", sourceHelpFlag)
 
@@ -419,23 +760,316 @@ func (cp *ciParser[T]) apply(result *T) (somethingSet bool, err error) {
 			return
 		}
 
-		association, ok := flagAssociation[f.Name]
+		association, ok := level.associations[f.Name]
 		if !ok {
 			return
 		}
 
-		v, _ := getField(result, association.path)
+		err = cp.record(setBy, f.Name, association, f.Value.String())
+	})
+
+	return help, err
+}
+
+// collectBound records which of the flags registered with RegisterFlags were set by the caller, checked in the same way as collect
+func (cp *ciParser[T]) collectBound(bound []*boundFlag) error {
+
+	setBy := map[string]setFlag{}
+	for _, b := range bound {
+		if !b.set || b.association.path == nil {
+			continue
+		}
+
+		if err := cp.record(setBy, b.name, b.association, b.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setFlag is the flag that set an option, used to find options set by both an alias and its replacement
+type setFlag struct {
+	flag  string
+	alias bool
+}
+
+// record adds the flag name (as it was typed) to the set flags, warning if it is a deprecated alias
+func (cp *ciParser[T]) record(setBy map[string]setFlag, name string, association cliAssociation, value string) error {
+
+	// an option may be set multiple times through its own name, short name or negation (last one wins), but not by an alias and its replacement
+	if previous, ok := setBy[association.name]; ok && (previous.alias || association.alias) && previous.flag != name {
+		return fmt.Errorf("%w: flags -%s and -%s are both set for the same option, only one may be used", errFatal, previous.flag, name)
+	}
+	setBy[association.name] = setFlag{flag: name, alias: association.alias}
+
+	if association.alias && association.deprecated {
+		cp.o.warn(fmt.Errorf("flag -%s is deprecated, use -%s instead", name, association.name))
+	}
+
+	cp.set = append(cp.set, cliFlagSet{cliAssociation: association, flag: name, value: value})
+	return nil
+}
+
+// parse registers and parses the cli flags, selecting any commands along the way
+// this happens before any source is applied, as the env and config file sources need to know which commands were selected
+func (cp *ciParser[T]) parse(result *T) error {
+	if cp.parsed {
+		return cp.parseErr
+	}
+	cp.parsed = true
+
+	cp.parseErr = cp.parseArgs(result)
+	return cp.parseErr
+}
+
+func (cp *ciParser[T]) parseArgs(result *T) error {
+
+	if bound := boundFlags(cp.o.flagSet()); len(bound) > 0 {
+		logger.Info("flag set was registered with RegisterFlags, using the values set by the caller", "flags", len(bound))
+
+		return cp.collectBound(bound)
+	}
+
+	level := cp.newLevel(cp.o.flagSet(), nil)
+
+	args := cp.o.arguments()
+	if len(args) == 0 {
+		logger.Info("no arguments supplied, not trying to parse cli")
+		// There were no args to parse, so the user must not be using the cli
+		return nil
+	}
+
+	// stop go flag from overwritting literally all configuration data on default write
+	cp.dummy = new(T)
+
+	for {
+		if err := cp.registerLevel(result, level); err != nil {
+			return err
+		}
+
+		var (
+			remaining []string
+			err       error
+		)
+		if level.gnu != nil {
+			remaining, err = level.gnu.parse(args)
+		} else {
+			err = level.fs.Parse(args)
+			remaining = level.fs.Args()
+		}
+		if err != nil {
+			return err
+		}
+
+		help, err := cp.collect(level)
+		if err != nil {
+			return err
+		}
+
+		if help {
+			level.fs.Usage()
+			return flag.ErrHelp
+		}
+
+		children := cp.o.commands.children(level.command)
+		idx := -1
+		if len(remaining) > 0 {
+			idx = slices.IndexFunc(children, func(cmd command) bool {
+				return cmd.name == remaining[0]
+			})
+		}
+
+		if idx == -1 {
+			if len(remaining) > 0 && len(children) > 0 && len(level.positional) == 0 {
+				return fmt.Errorf("unknown command %q", remaining[0])
+			}
+
+			cp.remaining, err = cp.bindPositional(level, remaining)
+			if err != nil {
+				return err
+			}
+			break
+		}
+
+		selected := children[idx]
+		logger.Info("command selected", "command", selected.name, "path", selected.path)
 
-		v.Set(association.v)
+		cp.o.commands.selected = append(cp.o.commands.selected, selected)
+		level = cp.newLevel(flag.NewFlagSet(selected.name, flag.ContinueOnError), &selected)
+		args = remaining[1:]
+	}
+
+	return nil
+}
+
+// bindPositional sets the positional fields of level from args, using the same conversion as flags. Returns the arguments that were not bound
+func (cp *ciParser[T]) bindPositional(level *cliLevel, args []string) (remaining []string, err error) {
+
+	bound := map[int]bool{}
+	highest := -1
+	for _, positional := range level.positional {
+		if positional.index < 0 {
+			continue
+		}
+
+		highest = max(highest, positional.index)
+		if positional.index >= len(args) {
+			continue
+		}
+
+		name := fmt.Sprintf("arg %d", positional.index)
+		if err := cp.setFromString(positional.field.value, positional.field.path, args[positional.index]); err != nil {
+			return nil, fmt.Errorf("invalid value %q for positional argument %d: %w", args[positional.index], positional.index, err)
+		}
+
+		bound[positional.index] = true
+		cp.set = append(cp.set, cliFlagSet{cliAssociation: cliAssociation{v: positional.field.value, path: positional.field.path, tag: positional.field.tag, name: name}, flag: name, value: args[positional.index]})
+	}
+
+	for _, positional := range level.positional {
+		if positional.index >= 0 {
+			continue
+		}
+
+		if highest+1 >= len(args) {
+			// nothing left for the rest of the arguments
+			return nil, nil
+		}
+
+		rest := args[highest+1:]
+		sliceValue := reflect.MakeSlice(positional.field.value.Type(), 0, len(rest))
+		for _, arg := range rest {
+			element := reflect.New(positional.field.value.Type().Elem()).Elem()
+			if err := cp.setFromString(element, positional.field.path, arg); err != nil {
+				return nil, fmt.Errorf("invalid value %q for positional arguments: %w", arg, err)
+			}
+			sliceValue = reflect.Append(sliceValue, element)
+		}
+		positional.field.value.Set(sliceValue)
+
+		cp.set = append(cp.set, cliFlagSet{cliAssociation: cliAssociation{v: positional.field.value, path: positional.field.path, tag: positional.field.tag, name: "args"}, flag: "args", value: strings.Join(rest, " ")})
+
+		// everything was consumed
+		return nil, nil
+	}
+
+	for i, arg := range args {
+		if !bound[i] {
+			remaining = append(remaining, arg)
+		}
+	}
+
+	return remaining, nil
+}
+
+// setFromString converts value in the same way as a flag of the same type would, and sets it
+func (cp *ciParser[T]) setFromString(target reflect.Value, path []string, value string) error {
+	fs := flag.NewFlagSet("positional", flag.ContinueOnError)
+	if !cp.registerFlag(fs, "value", "", target, path) {
+		return fmt.Errorf("unsupported type %s", target.Type())
+	}
+
+	return fs.Set("value", value)
+}
+
+func (cp *ciParser[T]) apply(result *T) (somethingSet bool, err error) {
+	if err := cp.parse(result); err != nil {
+		return false, err
+	}
+
+	if cp.o.cli.remaining != nil {
+		*cp.o.cli.remaining = cp.remaining
+	}
+
+	for _, set := range cp.set {
+		v, _ := getField(result, set.path)
+
+		v.Set(set.v)
 		somethingSet = true
+		cp.o.markSet(set.path)
+
+		logger.Info("CLI FLAG", "-"+set.flag, maskSensitive(set.value, set.tag))
+	}
+
+	return somethingSet, nil
+}
+
+// register adds the flags for the top level of T to fs without parsing, wrapping each so that confy can later tell which were set
+func (cp *ciParser[T]) register(fs *flag.FlagSet) error {
+	staging := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	level := cp.newLevel(staging, nil)
+
+	// the flags value types are recorded before they are wrapped, as the wrapper hides them from flag.UnquoteUsage
+	cp.dummy = new(T)
+	if err := cp.registerLevel(cp.dummy, level); err != nil {
+		return err
+	}
+
+	shorts := map[string]string{}
+	if level.gnu != nil {
+		for short, long := range level.gnu.shorts {
+			shorts[long] = short
+		}
+	}
+
+	var err error
+	staging.VisitAll(func(f *flag.Flag) {
+		if err != nil || f.Name == sourceHelpFlag {
+			return
+		}
 
-		logger.Info("CLI FLAG", "-"+f.Name, maskSensitive(f.Value.String(), association.tag))
+		if fs.Lookup(f.Name) != nil {
+			err = fmt.Errorf("%w: flag -%s is already defined", errFatal, f.Name)
+			return
+		}
+
+		typeName, _ := flag.UnquoteUsage(f)
+		if isBoolFlag(f) {
+			typeName = "bool"
+		}
+
+		fs.Var(&boundFlag{
+			Value:       f.Value,
+			association: level.associations[f.Name],
+			name:        f.Name,
+			short:       shorts[f.Name],
+			typeName:    typeName,
+			defValue:    f.DefValue,
+		}, f.Name, f.Usage)
 	})
 
-	if help {
-		cp.o.cli.commandLine.PrintDefaults()
-		return somethingSet, flag.ErrHelp
+	return err
+}
+
+// scanFlagValue finds the value of the flag name within args without needing every flag to be defined
+// accepts -name value, --name value, -name=value and --name=value. The last occurrence wins
+// Scanning stops at -- or at the first argument that is not a flag or a flag's value (e.g a positional argument or command), a value starting with - is taken to be the next flag
+func scanFlagValue(args []string, name string) (value string, found bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+
+		flagName, flagValue, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		if hasValue {
+			if flagName == name {
+				value, found = flagValue, true
+			}
+			continue
+		}
+
+		// without the flag definitions it is not known whether the flag takes a value, so the following argument is assumed to be one
+		if i+1 >= len(args) || strings.HasPrefix(args[i+1], "-") {
+			continue
+		}
+
+		i++
+		if flagName == name {
+			value, found = args[i], true
+		}
 	}
 
-	return somethingSet, nil
+	return value, found
 }
@@ -1,6 +1,7 @@
 package confy
 
 import (
+	"flag"
 	"os"
 	"reflect"
 	"strings"
@@ -196,3 +197,355 @@ func TestCliTransform(t *testing.T) {
 		}
 	}
 }
+
+func TestCliAliases(t *testing.T) {
+
+	os.Args = []string{"dummy", "-database.listen_port", "8080"}
+
+	dummyConfig, warnings, err := Config[aliasStruct](FromCli(CLIDelimiter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dummyConfig.Database.Port != 8080 {
+		t.Fatalf("%+v", dummyConfig)
+	}
+
+	if len(warnings) != 0 {
+		t.Fatalf("non-deprecated alias should not warn: %v", warnings)
+	}
+
+	os.Args = []string{"dummy", "-database.port", "8080", "-database.listen_port", "8081"}
+	_, _, err = Config[aliasStruct](FromCli(CLIDelimiter))
+	if err == nil {
+		t.Fatal("expected setting both the alias and the new name to be an error")
+	}
+}
+
+func TestCliAliasCollision(t *testing.T) {
+
+	type collidingAlias struct {
+		Host   string `confy:"host"`
+		Server string `confy:"server" confy_alias:"host"`
+	}
+
+	os.Args = []string{"dummy", "-server", "localhost"}
+	_, _, err := Config[collidingAlias](FromCli(CLIDelimiter))
+	if err == nil || !strings.Contains(err.Error(), "-host") {
+		t.Fatalf("expected an alias clashing with a flag to be an error, got %v", err)
+	}
+}
+
+type gnuStruct struct {
+	Database struct {
+		Port    int    `confy_short:"p"`
+		Host    string `confy_short:"H"`
+		MaxConn int
+	}
+	Verbose bool `confy_short:"v"`
+	Quiet   bool `confy_short:"q"`
+	Name    string
+}
+
+func TestCliShortFlagCollision(t *testing.T) {
+
+	type collidingShort struct {
+		Verbose bool   `confy_short:"v"`
+		Version string `confy_short:"v"`
+	}
+
+	os.Args = []string{"dummy", "-Verbose"}
+	_, _, err := Config[collidingShort](FromCli(CLIDelimiter))
+	if err == nil || !strings.Contains(err.Error(), "short flag -v") {
+		t.Fatalf("expected a repeated short flag to be an error, got %v", err)
+	}
+
+	_, _, err = Config[collidingShort](FromCli(CLIDelimiter), WithGNUFlags())
+	if err == nil {
+		t.Fatal("expected a repeated short flag to be an error with gnu flags")
+	}
+}
+
+func TestCliGNUFlags(t *testing.T) {
+
+	os.Args = []string{"dummy", "--database-port", "80", "-vq", "-Hlocalhost", "--database-max-conn=5", "positional", "--", "--name", "ignored"}
+
+	dummyConfig, _, err := Config[gnuStruct](FromCli(CLIDelimiter), WithGNUFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dummyConfig.Database.Port != 80 {
+		t.Errorf("expected port 80 got %d", dummyConfig.Database.Port)
+	}
+
+	if !dummyConfig.Verbose || !dummyConfig.Quiet {
+		t.Errorf("expected bundled short booleans to be set: %+v", dummyConfig)
+	}
+
+	if dummyConfig.Database.Host != "localhost" {
+		t.Errorf("expected attached short value got %q", dummyConfig.Database.Host)
+	}
+
+	if dummyConfig.Database.MaxConn != 5 {
+		t.Errorf("expected --flag=value to be parsed got %d", dummyConfig.Database.MaxConn)
+	}
+
+	if dummyConfig.Name != "" {
+		t.Errorf("flags after -- should not be parsed got %q", dummyConfig.Name)
+	}
+
+	os.Args = []string{"dummy", "-p", "8080", "-vx"}
+	_, _, err = Config[gnuStruct](FromCli(CLIDelimiter), WithGNUFlags())
+	if err == nil {
+		t.Fatal("expected undefined short flag to be an error")
+	}
+}
+
+func TestCliGNUNegativeNumbers(t *testing.T) {
+
+	type negativeStruct struct {
+		Offset int     `confy_short:"o"`
+		Scale  float64 `confy:"scale"`
+		Rest   []int   `confy_args:"rest"`
+	}
+
+	os.Args = []string{"dummy", "--offset", "-5", "--scale", "-0.5", "-3", "4"}
+	dummyConfig, _, err := Config[negativeStruct](FromCli(CLIDelimiter), WithGNUFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dummyConfig.Offset != -5 || dummyConfig.Scale != -0.5 || !reflect.DeepEqual(dummyConfig.Rest, []int{-3, 4}) {
+		t.Fatalf("negative numbers were not parsed as values: %+v", dummyConfig)
+	}
+
+	os.Args = []string{"dummy", "-o", "-7"}
+	dummyConfig, _, err = Config[negativeStruct](FromCli(CLIDelimiter), WithGNUFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dummyConfig.Offset != -7 {
+		t.Fatalf("expected -o -7 to set offset got %+v", dummyConfig)
+	}
+}
+
+func TestCliNegatedBools(t *testing.T) {
+
+	type negatable struct {
+		Enabled bool
+		Strict  bool `confy:";nonegate"`
+		Other   string
+	}
+
+	os.Args = []string{"dummy", "--enabled", "--no-enabled"}
+	dummyConfig, _, err := Config[negatable](FromCli(CLIDelimiter), WithGNUFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dummyConfig.Enabled {
+		t.Fatal("expected --no-enabled to turn off the flag")
+	}
+
+	os.Args = []string{"dummy", "-no-Enabled", "-Other", "set"}
+	dummyConfig, _, err = Config[negatable](FromCli(CLIDelimiter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dummyConfig.Enabled || dummyConfig.Other != "set" {
+		t.Fatalf("%+v", dummyConfig)
+	}
+
+	os.Args = []string{"dummy", "-no-Strict"}
+	_, _, err = Config[negatable](FromCli(CLIDelimiter))
+	if err == nil {
+		t.Fatal("expected nonegate field to not have a negated flag")
+	}
+
+	type collidingNegation struct {
+		DisableCache string `confy:"no-Cache"`
+		Cache        bool
+	}
+
+	os.Args = []string{"dummy", "-Cache"}
+	_, _, err = Config[collidingNegation](FromCli(CLIDelimiter))
+	if err == nil || !strings.Contains(err.Error(), "-no-Cache") {
+		t.Fatalf("expected a negated flag clashing with a flag to be an error, got %v", err)
+	}
+}
+
+func TestCliPositionalArguments(t *testing.T) {
+
+	type positional struct {
+		Verbose bool
+		Source  string `confy_arg:"0"`
+		Count   int    `confy_arg:"1"`
+	}
+
+	os.Args = []string{"dummy", "-Verbose", "input.txt", "3", "extra", "more"}
+
+	var remaining []string
+	config, _, err := Config[positional](FromCli(CLIDelimiter), WithRemainingArgs(&remaining))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !config.Verbose || config.Source != "input.txt" || config.Count != 3 {
+		t.Fatalf("%+v", config)
+	}
+
+	if !reflect.DeepEqual(remaining, []string{"extra", "more"}) {
+		t.Fatalf("expected leftover arguments got %v", remaining)
+	}
+
+	type rest struct {
+		Command string `confy_arg:"0"`
+		Ports   []int  `confy_args:"rest"`
+	}
+
+	os.Args = []string{"dummy", "--", "listen", "80", "443"}
+	restConfig, _, err := Config[rest](FromCli(CLIDelimiter), WithGNUFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restConfig.Command != "listen" || !reflect.DeepEqual(restConfig.Ports, []int{80, 443}) {
+		t.Fatalf("%+v", restConfig)
+	}
+
+	os.Args = []string{"dummy", "input.txt", "not_a_number"}
+	_, _, err = Config[positional](FromCli(CLIDelimiter))
+	if err == nil {
+		t.Fatal("expected type conversion error for positional argument")
+	}
+}
+
+func TestCliWithFlagSet(t *testing.T) {
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	manual := fs.String("manual", "", "hand written flag")
+
+	dummyConfig, _, err := Config[gnuStruct](FromCli(CLIDelimiter), WithFlagSet(fs), WithArgs([]string{"-manual", "value", "-Name", "confy"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *manual != "value" {
+		t.Errorf("expected hand written flag to be parsed alongside confys, got %q", *manual)
+	}
+
+	if dummyConfig.Name != "confy" {
+		t.Errorf("expected Name to be set got %q", dummyConfig.Name)
+	}
+}
+
+func TestCliScanFlagValue(t *testing.T) {
+
+	cases := []struct {
+		args  []string
+		value string
+		found bool
+	}{
+		{[]string{"-config", "a.json"}, "a.json", true},
+		{[]string{"--config=a.json", "-v", "--config", "b.json"}, "b.json", true},
+		{[]string{"-port", "80", "-config", "a.json"}, "a.json", true},
+		{[]string{"-config", "-v"}, "", false},
+		{[]string{"-v", "-config"}, "", false},
+		{[]string{"positional", "-config", "a.json"}, "", false},
+		{[]string{"-config", "a.json", "serve", "-config", "b.json"}, "a.json", true},
+		{[]string{"--", "-config", "a.json"}, "", false},
+	}
+
+	for _, c := range cases {
+		value, found := scanFlagValue(c.args, "config")
+		if value != c.value || found != c.found {
+			t.Errorf("%q: expected (%q, %t) got (%q, %t)", c.args, c.value, c.found, value, found)
+		}
+	}
+}
+
+func TestCliRegisterFlags(t *testing.T) {
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	manual := fs.Bool("manual", false, "hand written flag")
+
+	options := []OptionFunc{FromConfigFileFlagPath("config", "does_not_exist.json", "config file path", Auto), FromCli(CLIDelimiter)}
+	if err := RegisterFlags[testStruct](fs, options...); err != nil {
+		t.Fatal(err)
+	}
+
+	if fs.Lookup(sourceHelpFlag) != nil {
+		t.Fatal("the struct help flag should not be registered on a callers flag set")
+	}
+
+	if err := fs.Parse([]string{"-manual", "-config", "testdata/test.yaml", "-i_int", "7"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !*manual {
+		t.Fatal("hand written flag was not parsed")
+	}
+
+	// os.Args is not consulted as the caller already parsed the flag set
+	os.Args = []string{"dummy", "-i_int", "1"}
+	config, _, err := Config[testStruct](append(options, WithFlagSet(fs), WithConfigRequired())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.I != 7 {
+		t.Errorf("expected flag value 7 got %d", config.I)
+	}
+
+	if config.Thing != "example_string" {
+		t.Errorf("expected config path to be taken from the flag set: %+v", config)
+	}
+
+	if err := RegisterFlags[testStruct](fs, options...); err == nil {
+		t.Fatal("expected registering the same flags twice to fail")
+	}
+}
+
+func TestCliRegisterFlagsAliases(t *testing.T) {
+
+	options := []OptionFunc{FromCli(CLIDelimiter)}
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	if err := RegisterFlags[aliasStruct](fs, options...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Parse([]string{"-database.hostname", "old_host"}); err != nil {
+		t.Fatal(err)
+	}
+
+	config, warnings, err := Config[aliasStruct](append(options, WithFlagSet(fs))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Database.Host != "old_host" {
+		t.Fatalf("alias was not honoured: %+v", config)
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "-database.hostname is deprecated") {
+		t.Fatalf("expected a deprecation warning for the alias, got: %v", warnings)
+	}
+
+	fs = flag.NewFlagSet("app", flag.ContinueOnError)
+	if err := RegisterFlags[aliasStruct](fs, options...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Parse([]string{"-database.port", "8080", "-database.listen_port", "8081"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = Config[aliasStruct](append(options, WithFlagSet(fs))...)
+	if err == nil || !strings.Contains(err.Error(), "both set") {
+		t.Fatalf("expected setting both the alias and the new name to be an error, got: %v", err)
+	}
+}
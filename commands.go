@@ -0,0 +1,111 @@
+package confy
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// command is a struct field marked with confy_cmd:"name", its options are only populated when it is selected on the command line
+type command struct {
+	name string
+	// go field path to the command structure
+	path []string
+}
+
+type commandOptions struct {
+	// every command within the configuration structure
+	all []command
+	// the chain of selected commands, e.g [db, migrate] for "app db migrate"
+	selected []command
+
+	result *string
+}
+
+// getCommands returns every field tagged with confy_cmd within t
+func getCommands(t reflect.Type, path []string) (commands []command) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Type.Kind() != reflect.Struct {
+			continue
+		}
+
+		fieldPath := append(slices.Clone(path), field.Name)
+		if name, ok := field.Tag.Lookup(confyCmdTag); ok && name != "" {
+			commands = append(commands, command{name: name, path: fieldPath})
+		}
+
+		commands = append(commands, getCommands(field.Type, fieldPath)...)
+	}
+
+	return commands
+}
+
+func isPathPrefix(prefix, path []string) bool {
+	return len(prefix) <= len(path) && slices.Equal(prefix, path[:len(prefix)])
+}
+
+// owner returns the deepest command that contains the field at path, nil if the field belongs to the top level
+func (c *commandOptions) owner(path []string) *command {
+	var owner *command
+	for i := range c.all {
+		cmd := &c.all[i]
+		if len(cmd.path) < len(path) && isPathPrefix(cmd.path, path) && (owner == nil || len(cmd.path) > len(owner.path)) {
+			owner = cmd
+		}
+	}
+
+	return owner
+}
+
+// children returns the commands directly below parent (nil for the top level)
+func (c *commandOptions) children(parent *command) (children []command) {
+	for _, cmd := range c.all {
+		owner := c.owner(cmd.path)
+		if (owner == nil && parent == nil) || (owner != nil && parent != nil && slices.Equal(owner.path, parent.path)) {
+			children = append(children, cmd)
+		}
+	}
+
+	return children
+}
+
+func (c *commandOptions) isCommand(path []string) bool {
+	return slices.ContainsFunc(c.all, func(cmd command) bool {
+		return slices.Equal(cmd.path, path)
+	})
+}
+
+// inactive reports whether the field at path belongs to a command that was not selected, and thus should not be populated
+func (c *commandOptions) inactive(path []string) bool {
+	for _, cmd := range c.all {
+		if !isPathPrefix(cmd.path, path) {
+			continue
+		}
+
+		if !slices.ContainsFunc(c.selected, func(selected command) bool {
+			return slices.Equal(selected.path, cmd.path)
+		}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *commandOptions) selectedName() string {
+	var names []string
+	for _, cmd := range c.selected {
+		names = append(names, cmd.name)
+	}
+
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,72 @@
+package confy
+
+import (
+	"os"
+	"testing"
+)
+
+type commandStruct struct {
+	Verbose bool
+
+	Serve struct {
+		Port int
+		Host string
+	} `confy_cmd:"serve"`
+
+	Migrate struct {
+		Target string
+	} `confy_cmd:"migrate"`
+}
+
+func TestCommandSelection(t *testing.T) {
+
+	os.Args = []string{"dummy", "-Verbose", "serve", "-Port", "80"}
+	os.Setenv("serve_Host", "env_host")
+	os.Setenv("migrate_Target", "should_not_be_set")
+	defer os.Unsetenv("serve_Host")
+	defer os.Unsetenv("migrate_Target")
+
+	var selected string
+	config, _, err := Config[commandStruct](
+		FromConfigBytes([]byte(`{"migrate": {"Target": "file_target"}, "serve": {"Port": 22}}`), Json),
+		FromEnvs(ENVDelimiter),
+		FromCli(CLIDelimiter),
+		WithSelectedCommand(&selected),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if selected != "serve" {
+		t.Fatalf("expected serve to be selected got %q", selected)
+	}
+
+	if !config.Verbose || config.Serve.Port != 80 || config.Serve.Host != "env_host" {
+		t.Fatalf("global and command options were not populated: %+v", config)
+	}
+
+	if config.Migrate.Target != "" {
+		t.Fatalf("unselected command was populated: %+v", config)
+	}
+}
+
+func TestCommandGNU(t *testing.T) {
+
+	os.Args = []string{"dummy", "--verbose", "migrate", "--target", "v2"}
+
+	var selected string
+	config, _, err := Config[commandStruct](FromCli(CLIDelimiter), WithGNUFlags(), WithSelectedCommand(&selected))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if selected != "migrate" || config.Migrate.Target != "v2" || !config.Verbose {
+		t.Fatalf("selected %q: %+v", selected, config)
+	}
+
+	os.Args = []string{"dummy", "unknown"}
+	_, _, err = Config[commandStruct](FromCli(CLIDelimiter), WithGNUFlags())
+	if err == nil {
+		t.Fatal("expected unknown command to be an error")
+	}
+}
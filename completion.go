@@ -0,0 +1,358 @@
+package confy
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Shell selects the completion script GenerateCompletion writes
+type Shell string
+
+const (
+	Bash Shell = "bash"
+	Zsh  Shell = "zsh"
+	Fish Shell = "fish"
+)
+
+// completionFlag is an option as seen by a shell, every spelling of it and what its value can be
+type completionFlag struct {
+	// names are the flag, short and non deprecated aliases, including their dashes
+	names       []string
+	negation    string
+	description string
+
+	takesValue bool
+	allowed    []string
+	path       bool
+}
+
+type completionLevel struct {
+	// command is the chain of commands (e.g "db migrate"), empty for the top level
+	command string
+	parent  string
+	name    string
+
+	flags    []completionFlag
+	commands []HelpCommand
+}
+
+// GenerateCompletion writes a completion script for program to w, completing the flags confy generates for T (and its commands)
+// Values are completed from confy_oneof, file paths for fields with the path modifier and the FromConfigFileFlagPath flag
+// Pass the options given to Config so that the flag names match
+//
+//	bash: source <(app-completion bash)
+//	zsh:  save the output as _app within $fpath
+//	fish: save the output as ~/.config/fish/completions/app.fish
+func GenerateCompletion[T any](shell Shell, program string, w io.Writer, suppliedOptions ...OptionFunc) error {
+	if program == "" {
+		return fmt.Errorf("program name must not be empty")
+	}
+
+	data, err := referenceFor[T](suppliedOptions)
+	if err != nil {
+		return err
+	}
+
+	levels := completionLevels(data)
+
+	var script string
+	switch shell {
+	case Bash:
+		script = bashCompletion(program, levels)
+	case Zsh:
+		script = zshCompletion(program, levels)
+	case Fish:
+		script = fishCompletion(program, levels)
+	default:
+		return fmt.Errorf("unsupported shell %q, must be one of %s, %s or %s", shell, Bash, Zsh, Fish)
+	}
+
+	_, err = io.WriteString(w, script)
+	return err
+}
+
+func completionLevels(data []HelpData) []completionLevel {
+	var levels []completionLevel
+	for _, level := range data {
+		cl := completionLevel{
+			command:  level.Command,
+			commands: level.Commands,
+		}
+
+		if idx := strings.LastIndex(level.Command, " "); idx != -1 {
+			cl.parent, cl.name = level.Command[:idx], level.Command[idx+1:]
+		} else {
+			cl.name = level.Command
+		}
+
+		var options []HelpOption
+		for _, section := range level.Sections {
+			options = append(options, section.Options...)
+		}
+		options = append(options, level.Flags...)
+
+		for _, option := range options {
+			flag := completionFlag{
+				names:       []string{option.Flag},
+				negation:    option.Negation,
+				description: option.Description,
+				takesValue:  option.Type != "",
+				allowed:     option.Allowed,
+				path:        option.Path,
+			}
+
+			if option.Short != "" {
+				flag.names = append(flag.names, option.Short)
+			}
+
+			if !option.AliasesDeprecated {
+				flag.names = append(flag.names, option.Aliases...)
+			}
+
+			cl.flags = append(cl.flags, flag)
+		}
+
+		levels = append(levels, cl)
+	}
+
+	return levels
+}
+
+var nonIdentifier = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+func completionFunction(program string) string {
+	return "_" + nonIdentifier.ReplaceAllString(program, "_")
+}
+
+// shellQuote single quotes s for bash and zsh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func bashCompletion(program string, levels []completionLevel) string {
+	fn := completionFunction(program)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# bash completion for %s, generated by confy\n", program)
+	fmt.Fprintf(&sb, "%s() {\n", fn)
+	sb.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" cmd=\"\" i\n\n")
+	sb.WriteString("    # --flag=value is split on the =\n")
+	sb.WriteString("    if [[ \"$prev\" == \"=\" ]]; then\n        prev=\"${COMP_WORDS[COMP_CWORD-2]}\"\n    elif [[ \"$cur\" == \"=\" ]]; then\n        cur=\"\"\n    fi\n\n")
+
+	writeCommandScan(&sb, levels, "    for ((i = 1; i < COMP_CWORD; i++)); do\n        case \"$cmd:${COMP_WORDS[i]}\" in\n", "        esac\n    done\n", "            %s) cmd=%s ;;\n")
+
+	sb.WriteString("\n    case \"$cmd:$prev\" in\n")
+	for _, level := range levels {
+		for _, flag := range level.flags {
+			if !flag.takesValue {
+				continue
+			}
+
+			fmt.Fprintf(&sb, "        %s)\n", casePatterns(level.command, flag.names))
+			switch {
+			case len(flag.allowed) > 0:
+				fmt.Fprintf(&sb, "            COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(flag.allowed, " ")))
+			case flag.path:
+				sb.WriteString("            COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+			default:
+				sb.WriteString("            COMPREPLY=()\n")
+			}
+			sb.WriteString("            return ;;\n")
+		}
+	}
+	sb.WriteString("    esac\n\n")
+
+	sb.WriteString("    local words=\"\"\n    case \"$cmd\" in\n")
+	for _, level := range levels {
+		var words []string
+		for _, flag := range level.flags {
+			words = append(words, flag.names...)
+			if flag.negation != "" {
+				words = append(words, flag.negation)
+			}
+		}
+
+		for _, cmd := range level.commands {
+			words = append(words, cmd.Name)
+		}
+
+		fmt.Fprintf(&sb, "        %s) words=%s ;;\n", shellQuote(level.command), shellQuote(strings.Join(words, " ")))
+	}
+	sb.WriteString("    esac\n\n")
+	sb.WriteString("    COMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	sb.WriteString("}\n\n")
+	fmt.Fprintf(&sb, "complete -o default -F %s %s\n", fn, shellQuote(program))
+
+	return sb.String()
+}
+
+func zshCompletion(program string, levels []completionLevel) string {
+	fn := completionFunction(program)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#compdef %s\n", program)
+	fmt.Fprintf(&sb, "# zsh completion for %s, generated by confy\n\n", program)
+	fmt.Fprintf(&sb, "%s() {\n", fn)
+	sb.WriteString("    local cur=\"${words[CURRENT]}\" prev=\"${words[CURRENT-1]}\" cmd=\"\" i\n\n")
+
+	writeCommandScan(&sb, levels, "    for ((i = 2; i < CURRENT; i++)); do\n        case \"$cmd:${words[i]}\" in\n", "        esac\n    done\n", "            %s) cmd=%s ;;\n")
+
+	sb.WriteString("\n    case \"$cmd:$prev\" in\n")
+	for _, level := range levels {
+		for _, flag := range level.flags {
+			if !flag.takesValue {
+				continue
+			}
+
+			fmt.Fprintf(&sb, "        %s)\n", casePatterns(level.command, flag.names))
+			switch {
+			case len(flag.allowed) > 0:
+				var quoted []string
+				for _, value := range flag.allowed {
+					quoted = append(quoted, shellQuote(value))
+				}
+				fmt.Fprintf(&sb, "            compadd -- %s\n", strings.Join(quoted, " "))
+			case flag.path:
+				sb.WriteString("            _files\n")
+			default:
+				sb.WriteString("            _message 'value'\n")
+			}
+			sb.WriteString("            return ;;\n")
+		}
+	}
+	sb.WriteString("    esac\n\n")
+
+	sb.WriteString("    local -a completions\n    case \"$cmd\" in\n")
+	for _, level := range levels {
+		var completions []string
+		for _, flag := range level.flags {
+			for _, name := range flag.names {
+				completions = append(completions, shellQuote(zshDescribe(name, flag.description)))
+			}
+
+			if flag.negation != "" {
+				completions = append(completions, shellQuote(zshDescribe(flag.negation, "")))
+			}
+		}
+
+		for _, cmd := range level.commands {
+			completions = append(completions, shellQuote(zshDescribe(cmd.Name, cmd.Description)))
+		}
+
+		fmt.Fprintf(&sb, "        %s) completions=(%s) ;;\n", shellQuote(level.command), strings.Join(completions, " "))
+	}
+	sb.WriteString("    esac\n\n")
+	sb.WriteString("    _describe 'option' completions\n")
+	sb.WriteString("}\n\n")
+
+	fmt.Fprintf(&sb, "if [ \"$funcstack[1]\" = %q ]; then\n    %s \"$@\"\nelse\n    compdef %s %s\nfi\n", fn, fn, fn, shellQuote(program))
+
+	return sb.String()
+}
+
+// zshDescribe formats a name:description entry for _describe, colons in the name must be escaped
+func zshDescribe(name, description string) string {
+	name = strings.ReplaceAll(name, ":", `\:`)
+	if description == "" {
+		return name
+	}
+
+	return name + ":" + strings.ReplaceAll(description, "\n", " ")
+}
+
+func fishCompletion(program string, levels []completionLevel) string {
+	fn := completionFunction(program)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# fish completion for %s, generated by confy\n\n", program)
+
+	// reports whether the selected command chain is the first argument
+	fmt.Fprintf(&sb, "function %s_command\n", fn)
+	sb.WriteString("    set -l words (commandline -opc)\n    set -l cmd \"\"\n")
+	writeCommandScan(&sb, levels, "    for word in $words[2..-1]\n        switch \"$cmd:$word\"\n", "        end\n    end\n", "            case %s\n                set cmd %s\n")
+	sb.WriteString("    test \"$cmd\" = \"$argv[1]\"\nend\n\n")
+
+	fmt.Fprintf(&sb, "complete -c %s -f\n", fishQuote(program))
+	for _, level := range levels {
+		condition := fmt.Sprintf("-n %s", fishQuote(fmt.Sprintf("%s_command %s", fn, fishQuote(level.command))))
+
+		for _, flag := range level.flags {
+			var args []string
+			for _, name := range flag.names {
+				switch {
+				case strings.HasPrefix(name, "--"):
+					args = append(args, "-l", fishQuote(name[2:]))
+				case len([]rune(name)) == 2:
+					args = append(args, "-s", fishQuote(name[1:]))
+				default:
+					// go flag style long names with a single dash
+					args = append(args, "-o", fishQuote(name[1:]))
+				}
+			}
+
+			switch {
+			case !flag.takesValue:
+			case len(flag.allowed) > 0:
+				args = append(args, "-x", "-a", fishQuote(strings.Join(flag.allowed, " ")))
+			case flag.path:
+				args = append(args, "-r", "-F")
+			default:
+				args = append(args, "-x")
+			}
+
+			if flag.description != "" {
+				args = append(args, "-d", fishQuote(strings.ReplaceAll(flag.description, "\n", " ")))
+			}
+
+			fmt.Fprintf(&sb, "complete -c %s %s %s\n", fishQuote(program), condition, strings.Join(args, " "))
+
+			if flag.negation != "" {
+				option := "-o"
+				name := strings.TrimPrefix(flag.negation, "-")
+				if strings.HasPrefix(flag.negation, "--") {
+					option, name = "-l", flag.negation[2:]
+				}
+				fmt.Fprintf(&sb, "complete -c %s %s %s %s\n", fishQuote(program), condition, option, fishQuote(name))
+			}
+		}
+
+		for _, cmd := range level.commands {
+			args := []string{"-a", fishQuote(cmd.Name)}
+			if cmd.Description != "" {
+				args = append(args, "-d", fishQuote(cmd.Description))
+			}
+			fmt.Fprintf(&sb, "complete -c %s %s %s\n", fishQuote(program), condition, strings.Join(args, " "))
+		}
+	}
+
+	return sb.String()
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// writeCommandScan writes the loop that walks the words before the cursor to find the selected command chain, as a case statement of "parent:word" -> command chain
+func writeCommandScan(sb *strings.Builder, levels []completionLevel, start, end, entry string) {
+	sb.WriteString(start)
+	for _, level := range levels {
+		if level.command == "" {
+			continue
+		}
+
+		fmt.Fprintf(sb, entry, shellQuote(level.parent+":"+level.name), shellQuote(level.command))
+	}
+	sb.WriteString(end)
+}
+
+// casePatterns joins the names of a flag within command as case patterns matching "$cmd:$prev"
+func casePatterns(command string, names []string) string {
+	var patterns []string
+	for _, name := range names {
+		patterns = append(patterns, shellQuote(command+":"+name))
+	}
+
+	return strings.Join(patterns, "|")
+}
@@ -0,0 +1,66 @@
+package confy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type completionStruct struct {
+	Verbose bool   `confy_short:"v" confy_description:"verbose output"`
+	Level   string `confy:"level" confy_short:"l" confy_oneof:"debug,info"`
+	Cert    string `confy:"cert;path"`
+	Serve   struct {
+		Port int `confy_description:"listen port"`
+	} `confy_cmd:"serve" confy_description:"run the server"`
+}
+
+func TestGenerateCompletion(t *testing.T) {
+
+	for shell, expected := range map[Shell][]string{
+		Bash: {
+			"_my_app() {",
+			"':serve') cmd='serve' ;;",
+			"':--level'|':-l')\n            COMPREPLY=($(compgen -W 'debug info' -- \"$cur\"))",
+			"':--cert')\n            COMPREPLY=($(compgen -f -- \"$cur\"))",
+			"':--config')\n            COMPREPLY=($(compgen -f -- \"$cur\"))",
+			"'') words='--verbose -v --no-verbose --level -l --cert --config serve' ;;",
+			"'serve') words='--port' ;;",
+			"complete -o default -F _my_app 'my-app'",
+		},
+		Zsh: {
+			"#compdef my-app",
+			"compadd -- 'debug' 'info'",
+			"':--cert')\n            _files",
+			"'--verbose:verbose output'",
+			"'serve:run the server'",
+			"compdef _my_app 'my-app'",
+		},
+		Fish: {
+			"function _my_app_command",
+			"complete -c 'my-app' -n '_my_app_command \\'\\'' -l 'verbose' -s 'v' -d 'verbose output'",
+			"complete -c 'my-app' -n '_my_app_command \\'\\'' -l 'no-verbose'",
+			"-l 'level' -s 'l' -x -a 'debug info'",
+			"-l 'cert' -r -F",
+			"-l 'config' -r -F -d 'config file path'",
+			"-a 'serve' -d 'run the server'",
+			"complete -c 'my-app' -n '_my_app_command \\'serve\\'' -l 'port' -x -d 'listen port'",
+		},
+	} {
+		var b bytes.Buffer
+		if err := GenerateCompletion[completionStruct](shell, "my-app", &b, FromConfigFileFlagPath("config", "config.json", "config file path", Json), WithGNUFlags()); err != nil {
+			t.Fatal(err)
+		}
+
+		output := b.String()
+		for _, e := range expected {
+			if !strings.Contains(output, e) {
+				t.Fatalf("expected %q in %s completion:\n%s", e, shell, output)
+			}
+		}
+	}
+
+	if err := GenerateCompletion[completionStruct]("powershell", "my-app", &bytes.Buffer{}); err == nil {
+		t.Fatal("expected unsupported shell to fail")
+	}
+}
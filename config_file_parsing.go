@@ -1,9 +1,11 @@
 package confy
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 
@@ -239,6 +241,20 @@ func (cp *configParser[T]) apply(result *T) (somethingSet bool, err error) {
 		return false, err
 	}
 
+	if cp.o.config.caseInsensitive || hasAliases(reflect.TypeOf(clone)) {
+		configData, err = cp.normalizeKeys(configData, configType, reflect.TypeOf(clone).Elem())
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// read once so that the keys that are present can be found after decoding
+	data, err := io.ReadAll(configData)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config: %s", err)
+	}
+	configData = bytes.NewReader(data)
+
 	type configDecoder interface {
 		Decode(v any) (err error)
 	}
@@ -270,13 +286,26 @@ func (cp *configParser[T]) apply(result *T) (somethingSet bool, err error) {
 		return false, fmt.Errorf("failed to decode config: %s", err)
 	}
 
+	present := map[string]bool{}
+	if doc, tagName, err := decodeDocument(bytes.NewReader(data), configType); err == nil {
+		presentFields(doc, reflect.TypeOf(clone), tagName, nil, present)
+	}
+
 	fields := getFields(false, clone)
 
 	for _, value := range fields {
+		if cp.o.commands.inactive(value.path) {
+			continue
+		}
+
 		logger.Info("setting field of config file", "path", strings.Join(value.path, "."), "value", value.value.String(), "tag", value.tag)
 
 		if cp.setField(result, value.path, value.value) {
 			somethingSet = true
+
+			if present[strings.Join(value.path, ".")] {
+				cp.o.markSet(value.path)
+			}
 		}
 	}
 
@@ -379,10 +408,19 @@ func (cp *configParser[T]) createModifiedType(t reflect.Type) reflect.Type {
 			logger.Info("field had NO 'confy:' tag", "struct", t.Name(), "field", field.Name, "all_tags", field.Tag)
 		}
 
+		// commands have their own section in the config file, named after the command
+		if cmd, ok := field.Tag.Lookup(confyCmdTag); ok && cmd != "" && fieldMarshallingName == "" {
+			fieldMarshallingName = cmd
+		}
+
 		if fieldMarshallingName != "" {
 			for _, supportedTag := range cp.supportedTags {
 				confyTagNames[supportedTag] = fieldMarshallingName
 			}
+		} else if cp.o.config.naming != AsIs {
+			for _, supportedTag := range cp.supportedTags {
+				confyTagNames[supportedTag] = cp.o.config.naming.convert(field.Name)
+			}
 		} else {
 
 			// because the go-yaml parser only maps things automatically if they're lower case, add this
@@ -2,6 +2,7 @@ package confy
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -108,3 +109,96 @@ func TestDuplicateTagDetection(t *testing.T) {
 	LoadConfigFileAuto[duplicates]("testdata/duplicates.json", false)
 
 }
+
+func TestCaseInsensitiveKeys(t *testing.T) {
+
+	type caseStruct struct {
+		Database struct {
+			Host string
+			Port int
+		}
+		Renamed string `confy:"new_name"`
+	}
+
+	documents := map[ConfigType]string{
+		Json: `{"database": {"HOST": "localhost", "port": 5432}, "NEW_NAME": "renamed"}`,
+		Yaml: "database:\n  HOST: localhost\n  port: 5432\nNEW_NAME: renamed\n",
+		Toml: "NEW_NAME = \"renamed\"\n[database]\nHOST = \"localhost\"\nport = 5432\n",
+	}
+
+	for configType, doc := range documents {
+		config, _, err := Config[caseStruct](FromConfigBytes([]byte(doc), configType), WithCaseInsensitiveKeys(), WithStrictParsing())
+		if err != nil {
+			t.Fatalf("%s: %s", configType, err)
+		}
+
+		if config.Database.Host != "localhost" || config.Database.Port != 5432 || config.Renamed != "renamed" {
+			t.Fatalf("%s: keys were not matched case insensitively: %+v", configType, config)
+		}
+	}
+}
+
+func TestCaseInsensitiveKeysCollision(t *testing.T) {
+
+	type caseStruct struct {
+		Host string
+	}
+
+	config, warnings, err := Config[caseStruct](FromConfigBytes([]byte(`{"HOST": "upper", "Host": "exact"}`), Json), WithCaseInsensitiveKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Host != "exact" {
+		t.Fatalf("expected exact match to be preferred got %q", config.Host)
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("expected a collision warning got %v", warnings)
+	}
+}
+
+type aliasStruct struct {
+	Database struct {
+		Host string `confy:"host" confy_alias:"hostname,server;deprecated"`
+		Port int    `confy:"port" confy_alias:"listen_port"`
+	} `confy:"database"`
+}
+
+func TestConfigFileAliases(t *testing.T) {
+
+	config, warnings, err := Config[aliasStruct](FromConfigBytes([]byte(`{"database": {"server": "localhost", "listen_port": 5432}}`), Json), WithStrictParsing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Database.Host != "localhost" || config.Database.Port != 5432 {
+		t.Fatalf("aliases were not honoured: %+v", config)
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), `use "database.host" instead`) {
+		t.Fatalf("expected deprecation warning naming the replacement, got %v", warnings)
+	}
+
+	_, _, err = Config[aliasStruct](FromConfigBytes([]byte("database:\n  host: new\n  hostname: old\n"), Yaml))
+	if err == nil {
+		t.Fatal("expected setting both the alias and the new name to be an error")
+	}
+}
+
+type recursiveStruct struct {
+	Next *recursiveStruct
+	Name string `confy:"name" confy_alias:"title"`
+}
+
+func TestConfigFileRecursiveType(t *testing.T) {
+
+	config, _, err := Config[recursiveStruct](FromConfigBytes([]byte(`{"title": "head"}`), Json))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Name != "head" {
+		t.Fatalf("alias was not honoured on self referential type: %+v", config)
+	}
+}
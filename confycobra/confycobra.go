@@ -0,0 +1,125 @@
+// Package confycobra binds the flags confy generates to a github.com/spf13/cobra command, so that cobras help and completion list them
+//
+//	serve := &cobra.Command{Use: "serve"}
+//
+//	config, err := confycobra.Bind[Config](serve, confy.Defaults("config", "config.json"))
+//	...
+//	serve.RunE = func(cmd *cobra.Command, args []string) error {
+//		return run(config.Config)
+//	}
+package confycobra
+
+import (
+	"slices"
+
+	"github.com/NHAS/confy"
+	"github.com/NHAS/confy/confypflag"
+	"github.com/spf13/cobra"
+)
+
+// Binding holds the configuration populated when the command runs
+type Binding[T any] struct {
+	// Config is populated from the config file, env and flags before the commands Run/RunE
+	Config T
+	// Warnings are the non-fatal issues confy encountered while populating Config
+	Warnings []error
+}
+
+// Bind registers the flags confy generates for T as local flags of cmd, and populates the returned binding in cmd's PreRunE
+// opts are the same options given to confy.Config, they should include a cli source (FromCli or Defaults) for the flags to be used.
+// If no options are given env variables and cli flags are used
+// An existing PreRunE or PreRun on cmd is still run, after the config has been populated
+// Once the binding is populated the flags are reset, so that executing cmd again only uses the flags given to that execution, read them from the binding rather than cmd.Flags()
+func Bind[T any](cmd *cobra.Command, opts ...confy.OptionFunc) (*Binding[T], error) {
+	binding := &Binding[T]{}
+
+	load, err := register(cmd, false, binding, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, existingE := cmd.PreRun, cmd.PreRunE
+	cmd.PreRun = nil
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := load(); err != nil {
+			return err
+		}
+
+		if existingE != nil {
+			return existingE(cmd, args)
+		}
+
+		if existing != nil {
+			existing(cmd, args)
+		}
+		return nil
+	}
+
+	return binding, nil
+}
+
+// BindPersistent registers the flags confy generates for T as persistent flags of cmd, so they are also accepted by its subcommands
+// The binding is populated in cmd's PersistentPreRunE, note that cobra only runs the closest PersistentPreRunE so a subcommand that defines its own hides it
+// unless cobra.EnableTraverseRunHooks is set
+func BindPersistent[T any](cmd *cobra.Command, opts ...confy.OptionFunc) (*Binding[T], error) {
+	binding := &Binding[T]{}
+
+	load, err := register(cmd, true, binding, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, existingE := cmd.PersistentPreRun, cmd.PersistentPreRunE
+	cmd.PersistentPreRun = nil
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := load(); err != nil {
+			return err
+		}
+
+		if existingE != nil {
+			return existingE(cmd, args)
+		}
+
+		if existing != nil {
+			existing(cmd, args)
+		}
+		return nil
+	}
+
+	return binding, nil
+}
+
+// register adds the flags to cmd and returns the function that populates binding once cobra has parsed them
+func register[T any](cmd *cobra.Command, persistent bool, binding *Binding[T], opts []confy.OptionFunc) (func() error, error) {
+	if len(opts) == 0 {
+		opts = []confy.OptionFunc{confy.FromEnvs(confy.ENVDelimiter), confy.FromCli(confy.CLIDelimiter)}
+	}
+
+	fs := cmd.Flags()
+	if persistent {
+		fs = cmd.PersistentFlags()
+	}
+
+	fromFlags, err := confypflag.Register[T](fs, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		// cobra has already parsed the arguments, so confy is given none to stop it scanning os.Args for the config file flag
+		config, warnings, err := confy.Config[T](append(slices.Clone(opts), confy.WithArgs([]string{}), fromFlags)...)
+
+		// cobra does not clear flags between calls to Execute, so they are reset here for the next run to only see the flags it was given
+		if resetErr := confypflag.Reset(fs); resetErr != nil && err == nil {
+			err = resetErr
+		}
+
+		if err != nil {
+			return err
+		}
+
+		binding.Config = config
+		binding.Warnings = warnings
+		return nil
+	}, nil
+}
@@ -0,0 +1,124 @@
+package confycobra
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/NHAS/confy"
+	"github.com/spf13/cobra"
+)
+
+type testConfig struct {
+	Port     int    `confy:"port" confy_short:"p" confy_description:"port to listen on"`
+	Host     string `confy:"host"`
+	Database struct {
+		Name string
+	}
+}
+
+func TestBind(t *testing.T) {
+	cmd := &cobra.Command{Use: "serve"}
+
+	preRunCalled := false
+	cmd.PreRun = func(cmd *cobra.Command, args []string) {
+		preRunCalled = true
+	}
+
+	binding, err := Bind[testConfig](cmd, confy.FromEnvs(confy.ENVDelimiter), confy.FromCli(confy.CLIDelimiter), confy.WithEnviron(map[string]string{"host": "example.com"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ran testConfig
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ran = binding.Config
+		return nil
+	}
+
+	cmd.SetArgs([]string{"-p", "8080", "--database-name", "users"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !preRunCalled {
+		t.Fatal("existing PreRun was not called")
+	}
+
+	if ran.Port != 8080 || ran.Database.Name != "users" || ran.Host != "example.com" {
+		t.Fatalf("config was not populated before run: %+v", ran)
+	}
+}
+
+func TestBindExecuteTwice(t *testing.T) {
+	type repeatConfig struct {
+		Port  int    `confy:"port"`
+		Host  string `confy:"host"`
+		Ports []int  `confy:"ports"`
+	}
+
+	cmd := &cobra.Command{Use: "serve"}
+	binding, err := Bind[repeatConfig](cmd, confy.FromCli(confy.CLIDelimiter), confy.WithEnviron(map[string]string{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ran repeatConfig
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ran = binding.Config
+		return nil
+	}
+
+	cmd.SetArgs([]string{"--port", "8080", "--host", "first", "--ports", "1,2"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ran.Port != 8080 || ran.Host != "first" || len(ran.Ports) != 2 {
+		t.Fatalf("first execution was not populated: %+v", ran)
+	}
+
+	cmd.SetArgs([]string{"--host", "second", "--ports", "3"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ran.Port != 0 || ran.Host != "second" || len(ran.Ports) != 1 || ran.Ports[0] != 3 {
+		t.Fatalf("flags from the first execution leaked into the second: %+v", ran)
+	}
+}
+
+func TestBindPersistentHelp(t *testing.T) {
+	root := &cobra.Command{Use: "app"}
+
+	binding, err := BindPersistent[testConfig](root, confy.FromCli(confy.CLIDelimiter), confy.WithEnviron(map[string]string{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ran testConfig
+	child := &cobra.Command{Use: "child", Run: func(cmd *cobra.Command, args []string) {
+		ran = binding.Config
+	}}
+	root.AddCommand(child)
+
+	root.SetArgs([]string{"child", "--port=9000"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ran.Port != 9000 {
+		t.Fatalf("persistent flag was not populated for a subcommand: %+v", ran)
+	}
+
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"child", "--help"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "--port int") || !strings.Contains(out.String(), "port to listen on") {
+		t.Fatalf("expected cobra help to list confys flags:\n%s", out.String())
+	}
+}
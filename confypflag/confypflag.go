@@ -0,0 +1,91 @@
+// Package confypflag registers the flags confy generates on a github.com/spf13/pflag flag set
+//
+//	fs := pflag.NewFlagSet("app", pflag.ContinueOnError)
+//	verbose := fs.Bool("verbose", false, "hand written flag")
+//
+//	options := []confy.OptionFunc{confy.Defaults("config", "config.json")}
+//	fromFlags, err := confypflag.Register[Config](fs, options...)
+//	...
+//	fs.Parse(os.Args[1:])
+//
+//	config, warnings, err := confy.Config[Config](append(options, fromFlags)...)
+package confypflag
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/NHAS/confy"
+	"github.com/spf13/pflag"
+)
+
+// Register adds the flags confy generates for T to fs without parsing it, once fs is parsed pass the returned option to confy.Config
+// Flags are named and parsed in the GNU style (--database-port, -p for confy_short:"p"), naming can be changed with confy.WithNaming in opts
+// opts should match the options given to confy.Config so that the flag names agree
+func Register[T any](fs *pflag.FlagSet, opts ...confy.OptionFunc) (confy.OptionFunc, error) {
+	goFlags := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+
+	if err := confy.RegisterFlags[T](goFlags, append([]confy.OptionFunc{confy.WithGNUFlags()}, opts...)...); err != nil {
+		return nil, err
+	}
+
+	var flags []*pflag.Flag
+	for _, f := range all(goFlags) {
+		pf := pflag.PFlagFromGoFlag(f)
+		if v, ok := f.Value.(pflag.Value); ok {
+			// used directly rather than through pflag's wrapper so that Reset can find it
+			pf.Value = v
+		}
+
+		if s, ok := f.Value.(interface{ Shorthand() string }); ok && s.Shorthand() != "" {
+			pf.Shorthand = s.Shorthand()
+		}
+
+		// pflag panics on redefinition, so check everything before adding anything
+		if fs.Lookup(pf.Name) != nil {
+			return nil, fmt.Errorf("flag --%s is already defined", pf.Name)
+		}
+
+		if pf.Shorthand != "" && fs.ShorthandLookup(pf.Shorthand) != nil {
+			return nil, fmt.Errorf("short flag -%s for --%s is already defined", pf.Shorthand, pf.Name)
+		}
+
+		flags = append(flags, pf)
+	}
+
+	for _, pf := range flags {
+		fs.AddFlag(pf)
+	}
+
+	return confy.WithFlagSet(goFlags), nil
+}
+
+// Reset marks the flags Register added to fs as not set and restores their defaults, so that fs can be parsed again
+// e.g when the same cobra command is executed more than once
+func Reset(fs *pflag.FlagSet) error {
+	var errs []error
+	fs.VisitAll(func(pf *pflag.Flag) {
+		r, ok := pf.Value.(interface{ Reset() error })
+		if !ok {
+			return
+		}
+
+		if err := r.Reset(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to reset --%s: %w", pf.Name, err))
+			return
+		}
+
+		pf.Changed = false
+	})
+
+	return errors.Join(errs...)
+}
+
+func all(fs *flag.FlagSet) (flags []*flag.Flag) {
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+
+	return flags
+}
@@ -0,0 +1,96 @@
+package confypflag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NHAS/confy"
+	"github.com/spf13/pflag"
+)
+
+type testConfig struct {
+	Port     int    `confy:"port" confy_short:"p"`
+	Host     string `confy:"host"`
+	Debug    bool   `confy:"debug"`
+	Database struct {
+		Name string
+	}
+}
+
+func TestRegister(t *testing.T) {
+	fs := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	verbose := fs.BoolP("verbose", "v", false, "hand written flag")
+
+	fromFlags, err := Register[testConfig](fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fs.Lookup("database-name") == nil {
+		t.Fatal("expected kebab case flag database-name to be registered")
+	}
+
+	if err := fs.Parse([]string{"-vp", "8080", "--debug", "--database-name=users", "positional"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !*verbose {
+		t.Fatal("hand written flag was not parsed")
+	}
+
+	result, _, err := confy.Config[testConfig](confy.FromCli(confy.CLIDelimiter), confy.WithEnviron(map[string]string{}), fromFlags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Port != 8080 || !result.Debug || result.Database.Name != "users" {
+		t.Fatalf("values were not taken from the parsed flag set: %+v", result)
+	}
+
+	if result.Host != "" {
+		t.Fatalf("unset flag should not change the config: %+v", result)
+	}
+}
+
+func TestRegisterConflict(t *testing.T) {
+	fs := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	fs.StringP("other", "p", "", "takes the short name")
+
+	if _, err := Register[testConfig](fs); err == nil {
+		t.Fatal("expected conflicting short flag to be an error")
+	}
+
+	if fs.Lookup("port") != nil {
+		t.Fatal("no flags should be added when registration fails")
+	}
+}
+
+func TestRegisterDeprecatedAlias(t *testing.T) {
+
+	type aliasConfig struct {
+		Host string `confy:"host" confy_alias:"hostname;deprecated"`
+	}
+
+	fs := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	fromFlags, err := Register[aliasConfig](fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Parse([]string{"--hostname", "old_host"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, warnings, err := confy.Config[aliasConfig](confy.FromCli(confy.CLIDelimiter), confy.WithEnviron(map[string]string{}), fromFlags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Host != "old_host" {
+		t.Fatalf("alias was not honoured: %+v", result)
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "-hostname is deprecated") {
+		t.Fatalf("expected a deprecation warning for the alias, got: %v", warnings)
+	}
+}
@@ -0,0 +1,17 @@
+module github.com/NHAS/confy/confypflag
+
+go 1.23.2
+
+require (
+	github.com/NHAS/confy v0.0.0-00010101000000-000000000000
+	github.com/spf13/pflag v1.0.10
+)
+
+require (
+	github.com/fsnotify/fsnotify v1.10.1 // indirect
+	github.com/pelletier/go-toml/v2 v2.2.3 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/NHAS/confy => ../
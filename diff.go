@@ -0,0 +1,205 @@
+package confy
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "added"
+	Removed  ChangeKind = "removed"
+	Modified ChangeKind = "modified"
+)
+
+// Change is a single difference found by Diff
+type Change struct {
+	// Path is the dotted confy path of the value (e.g "database.port"), fields are named as their confy tag renames them, map keys and slice indexes are path components
+	Path string
+	Kind ChangeKind
+
+	// Old is nil for Added, New is nil for Removed. Values of fields with the sensitive modifier are redacted
+	Old any
+	New any
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s added: %v", c.Path, c.New)
+	case Removed:
+		return fmt.Sprintf("%s removed: %v", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("%s: %v -> %v", c.Path, c.Old, c.New)
+	}
+}
+
+// Diff returns the differences between a and b, in field order
+// Nested structs are compared field by field, map entries by key and slices by index, so that only the values that changed are reported
+// Values of fields with the sensitive modifier (and anything within them) are replaced with asterisks
+func Diff[T any](a, b T) []Change {
+	var changes []Change
+	for _, entry := range diffValues(reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem()) {
+		changes = append(changes, entry.Change)
+	}
+
+	return changes
+}
+
+// diffEntry is a change along with the confy path (e.g ["database", "port"]) of the struct field it is within, which unlike Change.Path has no map keys or slice indexes
+type diffEntry struct {
+	Change
+	field []string
+
+	// static is the confy path of the field with the static modifier the change is within, nil if there is none
+	static []string
+}
+
+func diffValues(a, b reflect.Value) []diffEntry {
+	d := differ{}
+	d.diff(a, b, nil, nil, nil, false)
+
+	return d.entries
+}
+
+type differ struct {
+	entries []diffEntry
+}
+
+func (d *differ) add(kind ChangeKind, a, b reflect.Value, path, field, static []string, sensitive bool) {
+	d.entries = append(d.entries, diffEntry{
+		Change: Change{
+			Path: strings.Join(path, "."),
+			Kind: kind,
+			Old:  redactedValue(a, sensitive),
+			New:  redactedValue(b, sensitive),
+		},
+		field:  field,
+		static: static,
+	})
+}
+
+func (d *differ) diff(a, b reflect.Value, path, field, static []string, sensitive bool) {
+	if a.Kind() == reflect.Ptr || a.Kind() == reflect.Interface {
+		switch {
+		case a.IsNil() && b.IsNil():
+		case a.IsNil():
+			d.add(Added, reflect.Value{}, b.Elem(), path, field, static, sensitive)
+		case b.IsNil():
+			d.add(Removed, a.Elem(), reflect.Value{}, path, field, static, sensitive)
+		case a.Elem().Type() != b.Elem().Type():
+			d.add(Modified, a.Elem(), b.Elem(), path, field, static, sensitive)
+		default:
+			d.diff(a.Elem(), b.Elem(), path, field, static, sensitive)
+		}
+		return
+	}
+
+	switch {
+	case a.Kind() == reflect.Struct && !encodesItself(a.Type()):
+		for i := 0; i < a.NumField(); i++ {
+			sf := a.Type().Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+
+			fieldPath := append(slices.Clip(field), resolveName(sf))
+
+			fieldStatic := static
+			if fieldStatic == nil && hasModifier(sf.Tag, confyTag, "static") {
+				fieldStatic = fieldPath
+			}
+
+			d.diff(a.Field(i), b.Field(i),
+				append(slices.Clip(path), resolveName(sf)),
+				fieldPath,
+				fieldStatic,
+				sensitive || hasModifier(sf.Tag, confyTag, "sensitive"),
+			)
+		}
+	case a.Kind() == reflect.Map:
+		keys := a.MapKeys()
+		for _, key := range b.MapKeys() {
+			if !a.MapIndex(key).IsValid() {
+				keys = append(keys, key)
+			}
+		}
+
+		// map iteration order is random, sort so that the changes are stable
+		slices.SortFunc(keys, func(x, y reflect.Value) int {
+			return strings.Compare(fmt.Sprint(x.Interface()), fmt.Sprint(y.Interface()))
+		})
+
+		for _, key := range keys {
+			keyPath := append(slices.Clip(path), fmt.Sprint(key.Interface()))
+
+			av, bv := a.MapIndex(key), b.MapIndex(key)
+			switch {
+			case !av.IsValid():
+				d.add(Added, reflect.Value{}, bv, keyPath, field, static, sensitive)
+			case !bv.IsValid():
+				d.add(Removed, av, reflect.Value{}, keyPath, field, static, sensitive)
+			default:
+				d.diff(av, bv, keyPath, field, static, sensitive)
+			}
+		}
+	case a.Kind() == reflect.Slice || a.Kind() == reflect.Array:
+		for i := 0; i < max(a.Len(), b.Len()); i++ {
+			indexPath := append(slices.Clip(path), strconv.Itoa(i))
+
+			switch {
+			case i >= a.Len():
+				d.add(Added, reflect.Value{}, b.Index(i), indexPath, field, static, sensitive)
+			case i >= b.Len():
+				d.add(Removed, a.Index(i), reflect.Value{}, indexPath, field, static, sensitive)
+			default:
+				d.diff(a.Index(i), b.Index(i), indexPath, field, static, sensitive)
+			}
+		}
+	default:
+		if !equalValues(a, b) {
+			d.add(Modified, a, b, path, field, static, sensitive)
+		}
+	}
+}
+
+// equalValues compares a and b with their Equal method if the type has one (e.g time.Time, where the monotonic clock reading and location pointer can differ for the same instant), otherwise with reflect.DeepEqual
+func equalValues(a, b reflect.Value) bool {
+	if method := a.MethodByName("Equal"); method.IsValid() {
+		mt := method.Type()
+		if mt.NumIn() == 1 && mt.NumOut() == 1 && mt.In(0) == a.Type() && mt.Out(0).Kind() == reflect.Bool {
+			return method.Call([]reflect.Value{b})[0].Bool()
+		}
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func redactedValue(v reflect.Value, sensitive bool) any {
+	if !v.IsValid() {
+		return nil
+	}
+
+	if sensitive && !v.IsZero() {
+		return "**********"
+	}
+
+	return v.Interface()
+}
+
+// changedFields returns the dotted confy paths of the struct fields that entries are within, without duplicates
+func changedFields(entries []diffEntry) []string {
+	var fields []string
+	for _, entry := range entries {
+		field := strings.Join(entry.field, ".")
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
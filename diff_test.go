@@ -0,0 +1,82 @@
+package confy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type diffStruct struct {
+	Listen   string `confy:"listen"`
+	Password string `confy:"password;sensitive"`
+	Database struct {
+		Port int `confy:"port"`
+	} `confy:"database"`
+	Limits  *struct{ Requests int }
+	Hosts   []string
+	Headers map[string]string
+}
+
+func TestDiff(t *testing.T) {
+
+	var a diffStruct
+	a.Listen = ":80"
+	a.Password = "hunter2"
+	a.Database.Port = 5432
+	a.Hosts = []string{"a", "b"}
+	a.Headers = map[string]string{"X-Old": "1", "X-Same": "2"}
+
+	b := a
+	b.Listen = ":8080"
+	b.Password = "correct horse"
+	b.Database.Port = 5433
+	b.Limits = &struct{ Requests int }{Requests: 10}
+	b.Hosts = []string{"a"}
+	b.Headers = map[string]string{"X-New": "3", "X-Same": "2"}
+
+	expected := []Change{
+		{Path: "listen", Kind: Modified, Old: ":80", New: ":8080"},
+		{Path: "password", Kind: Modified, Old: "**********", New: "**********"},
+		{Path: "database.port", Kind: Modified, Old: 5432, New: 5433},
+		{Path: "Limits", Kind: Added, New: struct{ Requests int }{Requests: 10}},
+		{Path: "Hosts.1", Kind: Removed, Old: "b"},
+		{Path: "Headers.X-New", Kind: Added, New: "3"},
+		{Path: "Headers.X-Old", Kind: Removed, Old: "1"},
+	}
+
+	changes := Diff(a, b)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("unexpected changes:\n%v\nexpected:\n%v", changes, expected)
+	}
+
+	if changes[0].String() != "listen: :80 -> :8080" {
+		t.Fatalf("unexpected change string: %s", changes[0])
+	}
+
+	if changes := Diff(a, a); len(changes) != 0 {
+		t.Fatalf("expected no changes comparing a config to itself, got: %v", changes)
+	}
+}
+
+func TestDiffEqualMethod(t *testing.T) {
+
+	type timeStruct struct {
+		Started time.Time
+		Expires time.Time
+	}
+
+	now := time.Now()
+	a := timeStruct{Started: now, Expires: now.Add(time.Hour)}
+
+	// the same instants, without the monotonic clock reading and in another location
+	b := timeStruct{Started: now.Round(0), Expires: now.Add(time.Hour).In(time.FixedZone("other", 3600))}
+
+	if changes := Diff(a, b); len(changes) != 0 {
+		t.Fatalf("expected equal times to be unchanged, got: %v", changes)
+	}
+
+	b.Expires = b.Expires.Add(time.Minute)
+	if changes := Diff(a, b); len(changes) != 1 || changes[0].Path != "Expires" {
+		t.Fatalf("expected only Expires to change, got: %v", changes)
+	}
+}
@@ -0,0 +1,242 @@
+package confy
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GenerateMarkdown writes a reference of every option of T, and of each of its commands, as markdown tables
+// Each option lists its cli flag, env variable, config file key, type, default and description, named exactly as Config with the same options would look for them
+// This is intended to be run from go generate, so that documentation stays in step with the configuration structure
+func GenerateMarkdown[T any](w io.Writer, suppliedOptions ...OptionFunc) error {
+	levels, err := referenceFor[T](suppliedOptions)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for _, level := range levels {
+		if level.Command == "" {
+			sb.WriteString("## Options\n")
+		} else {
+			fmt.Fprintf(&sb, "## Command `%s`\n", level.Command)
+		}
+
+		if level.Description != "" {
+			fmt.Fprintf(&sb, "\n%s\n", level.Description)
+		}
+
+		sections := level.Sections
+		if len(level.Flags) > 0 {
+			sections = append(sections, HelpSection{Title: "Other options", Options: level.Flags})
+		}
+
+		for _, section := range sections {
+			if section.Title != "" {
+				fmt.Fprintf(&sb, "\n### %s\n", section.Title)
+			}
+
+			if section.Description != "" {
+				fmt.Fprintf(&sb, "\n%s\n", section.Description)
+			}
+
+			sb.WriteString("\n| Flag | Env | File key | Type | Default | Description |\n")
+			sb.WriteString("|------|-----|----------|------|---------|-------------|\n")
+			for _, option := range section.Options {
+				flag := option.Flag
+				if option.Short != "" {
+					flag = option.Short + ", " + flag
+				}
+
+				fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s | %s |\n",
+					markdownCode(flag),
+					markdownCode(option.Env),
+					markdownCode(option.FileKey),
+					markdownEscape(option.Type),
+					markdownCode(option.Default),
+					markdownEscape(referenceDescription(option)),
+				)
+			}
+		}
+
+		sb.WriteString("\n")
+	}
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// GenerateManPage writes the OPTIONS section (and a COMMANDS section if T has commands) of a roff man page describing every option of T
+// The output is meant to be included in a hand written page, e.g after the NAME, SYNOPSIS and DESCRIPTION sections
+func GenerateManPage[T any](w io.Writer, suppliedOptions ...OptionFunc) error {
+	levels, err := referenceFor[T](suppliedOptions)
+	if err != nil {
+		return err
+	}
+
+	var (
+		sb       strings.Builder
+		commands bool
+	)
+	for _, level := range levels {
+		if level.Command == "" {
+			sb.WriteString(".SH OPTIONS\n")
+		} else {
+			if !commands {
+				sb.WriteString(".SH COMMANDS\n")
+				commands = true
+			}
+			fmt.Fprintf(&sb, ".SS %s\n", roffEscape(level.Command))
+		}
+
+		if level.Description != "" {
+			fmt.Fprintf(&sb, ".PP\n%s\n", roffEscape(level.Description))
+		}
+
+		sections := level.Sections
+		if len(level.Flags) > 0 {
+			sections = append(sections, HelpSection{Title: "Other options", Options: level.Flags})
+		}
+
+		for _, section := range sections {
+			if section.Title != "" {
+				if level.Command == "" {
+					fmt.Fprintf(&sb, ".SS %s\n", roffEscape(section.Title))
+				} else {
+					fmt.Fprintf(&sb, ".PP\n\\fI%s\\fR\n", roffEscape(section.Title))
+				}
+			}
+
+			if section.Description != "" {
+				fmt.Fprintf(&sb, ".PP\n%s\n", roffEscape(section.Description))
+			}
+
+			for _, option := range section.Options {
+				sb.WriteString(".TP\n")
+
+				if option.Short != "" {
+					fmt.Fprintf(&sb, "\\fB%s\\fR, ", roffEscape(option.Short))
+				}
+				fmt.Fprintf(&sb, "\\fB%s\\fR", roffEscape(option.Flag))
+				if option.Type != "" {
+					fmt.Fprintf(&sb, " \\fI%s\\fR", roffEscape(option.Type))
+				}
+				sb.WriteString("\n")
+
+				if description := referenceDescription(option); description != "" {
+					fmt.Fprintf(&sb, "%s\n", roffEscape(description))
+				}
+
+				var sources []string
+				if option.Env != "" {
+					sources = append(sources, fmt.Sprintf("env \\fB%s\\fR", roffEscape(option.Env)))
+				}
+
+				if option.FileKey != "" {
+					sources = append(sources, fmt.Sprintf("file key \\fB%s\\fR", roffEscape(option.FileKey)))
+				}
+
+				if option.Default != "" {
+					sources = append(sources, fmt.Sprintf("default \\fI%s\\fR", roffEscape(option.Default)))
+				}
+
+				if len(sources) > 0 {
+					fmt.Fprintf(&sb, ".br\n%s\n", strings.Join(sources, ", "))
+				}
+			}
+		}
+	}
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// referenceFor describes the top level of T followed by each of its commands
+func referenceFor[T any](suppliedOptions []OptionFunc) ([]HelpData, error) {
+	cp, err := newHelpParser[T](suppliedOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	top, err := cp.levelHelp(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := []HelpData{top}
+	for i := range cp.o.commands.all {
+		level, err := cp.levelHelp(&cp.o.commands.all[i])
+		if err != nil {
+			return nil, err
+		}
+
+		levels = append(levels, level)
+	}
+
+	return levels, nil
+}
+
+// referenceDescription is the description of an option followed by its constraints and aliases
+func referenceDescription(option HelpOption) string {
+	var details []string
+	if len(option.Allowed) > 0 {
+		details = append(details, "one of: "+strings.Join(option.Allowed, ", "))
+	}
+
+	if option.Negation != "" {
+		details = append(details, "negate with "+option.Negation)
+	}
+
+	if len(option.Aliases) > 0 {
+		label := "aliases: "
+		if option.AliasesDeprecated {
+			label = "deprecated aliases: "
+		}
+		details = append(details, label+strings.Join(option.Aliases, ", "))
+	}
+
+	if option.Required {
+		details = append(details, "required")
+	}
+
+	if option.Sensitive {
+		details = append(details, "sensitive")
+	}
+
+	if option.Static {
+		details = append(details, "requires restart")
+	}
+
+	description := option.Description
+	if len(details) > 0 {
+		if description != "" {
+			description += " "
+		}
+		description += "(" + strings.Join(details, ", ") + ")"
+	}
+
+	return description
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return "`" + strings.ReplaceAll(markdownEscape(s), "`", "'") + "`"
+}
+
+// roffEscape escapes text for use in roff, so that backslashes, dashes and leading control characters are printed as is
+func roffEscape(s string) string {
+	s = strings.NewReplacer("\\", "\\e", "-", "\\-", "\n", " ").Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = "\\&" + s
+	}
+
+	return s
+}
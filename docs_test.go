@@ -0,0 +1,65 @@
+package confy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type docsStruct struct {
+	Verbose  bool   `confy_short:"v" confy_description:"verbose | chatty output"`
+	Level    string `confy:"level;required" confy_oneof:"debug,info"`
+	Database struct {
+		Port int `confy_description:"port to connect to"`
+	} `confy_description:"database settings"`
+	Serve struct {
+		Port int `confy_description:"listen port"`
+	} `confy_cmd:"serve" confy_description:"run the server"`
+}
+
+func TestGenerateMarkdown(t *testing.T) {
+
+	var b bytes.Buffer
+	if err := GenerateMarkdown[docsStruct](&b); err != nil {
+		t.Fatal(err)
+	}
+
+	output := b.String()
+	for _, expected := range []string{
+		"## Options",
+		"| `-v, -Verbose` | `Verbose` | `Verbose` |  |  | verbose \\| chatty output (negate with -no-Verbose) |",
+		"| `-level` | `level` | `level` | string |  | (one of: debug, info, required) |",
+		"### Database\n\ndatabase settings",
+		"| `-Database.Port` | `Database_Port` | `Database.Port` | int |  | port to connect to |",
+		"| `-config` |  |  | string | `config.json` | config file path |",
+		"## Command `serve`\n\nrun the server",
+		"| `-Port` | `serve_Port` | `serve.Port` | int |  | listen port |",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in markdown:\n%s", expected, output)
+		}
+	}
+}
+
+func TestGenerateManPage(t *testing.T) {
+
+	var b bytes.Buffer
+	if err := GenerateManPage[docsStruct](&b, FromEnvs(ENVDelimiter), FromCli(CLIDelimiter), WithGNUFlags()); err != nil {
+		t.Fatal(err)
+	}
+
+	output := b.String()
+	for _, expected := range []string{
+		".SH OPTIONS\n.TP\n\\fB\\-v\\fR, \\fB\\-\\-verbose\\fR\n",
+		"\\fB\\-\\-database\\-port\\fR \\fIint\\fR\nport to connect to\n.br\nenv \\fBDatabase_Port\\fR\n",
+		".SH COMMANDS\n.SS serve\n.PP\nrun the server\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in man page:\n%s", expected, output)
+		}
+	}
+
+	if strings.Contains(output, "file key") {
+		t.Fatalf("file keys should not be listed when the config file source is not used:\n%s", output)
+	}
+}
@@ -2,38 +2,67 @@ package confy
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
-	"net/http"
-	"net/url"
+	"maps"
 	"os"
 	"path/filepath"
 	"reflect"
 	"slices"
 	"strings"
-	"time"
+	"text/template"
 )
 
 type OptionFunc func(*options) error
 
 type configDataOptions struct {
-	strictParsing bool
-	required      bool
+	// the cli flag set by FromConfigFileFlagPath
+	pathFlag            string
+	pathFlagDefault     string
+	pathFlagDescription string
+
+	strictParsing   bool
+	required        bool
+	caseInsensitive bool
+	naming          NamingStrategy
 
 	dataMethod func() (io.Reader, ConfigType, error)
+	// filePath returns the path of the config file when it is loaded from disk, used by Watch
+	filePath func() string
+	// url is the state of the FromConfigURL source, used by Watch
+	url *remoteConfig
+	// loaded is called once the config from dataMethod has been decoded and the result has passed validation, e.g to keep a url response as the last good config
+	loaded func() error
+
+	remote remoteOptions
 }
 
 type envOptions struct {
-	delimiter string
-	transform Transform
+	delimiter       string
+	prefix          string
+	caseInsensitive bool
+	naming          NamingStrategy
+	transform       Transform
 }
 
 type cliOptions struct {
 	envOptions
 	commandLine *flag.FlagSet
+	// commandLine was supplied by WithFlagSet
+	external bool
+
+	gnu bool
+
+	remaining *[]string
+
+	helpTemplate *template.Template
+
+	// the flag set by WithPrintConfigFlag
+	printFlag string
 }
 
 type options struct {
@@ -42,8 +71,114 @@ type options struct {
 	cli cliOptions
 	env envOptions
 
+	commands commandOptions
+
+	watch watchOptions
+
 	order        []preference
 	currentlySet map[preference]bool
+
+	// fieldsSet holds the go paths (e.g Database.Port) of the fields a source set, even to a zero value
+	fieldsSet map[string]bool
+
+	// non-fatal issues raised by the sources while populating the config
+	warnings []error
+
+	// args are the cli arguments without the program name, os.Args[1:] is used if not set
+	args    []string
+	argsSet bool
+
+	// lookupEnv and environ replace os.LookupEnv and os.Environ when set
+	lookupEnv func(string) (string, bool)
+	environ   func() []string
+
+	// ctx is the context of the load these options are for, requests made by sources (e.g FromConfigURL) stop when it is done
+	ctx context.Context
+}
+
+// arguments returns the cli arguments to parse, without the program name
+func (o *options) arguments() []string {
+	if o.argsSet {
+		return o.args
+	}
+
+	if len(os.Args) == 0 {
+		return nil
+	}
+
+	return os.Args[1:]
+}
+
+func (o *options) programName() string {
+	if len(os.Args) == 0 {
+		return "confy"
+	}
+
+	return filepath.Base(os.Args[0])
+}
+
+// flagSet returns the flag set that cli flags are registered on, creating it if required
+func (o *options) flagSet() *flag.FlagSet {
+	if o.cli.commandLine == nil {
+		o.cli.commandLine = flag.NewFlagSet(o.programName(), flag.ContinueOnError)
+	}
+
+	return o.cli.commandLine
+}
+
+// boundFlagValue returns the value of a flag registered with RegisterFlags, if the caller set it when parsing
+func (o *options) boundFlagValue(name string) (string, bool) {
+	if o.cli.commandLine == nil {
+		return "", false
+	}
+
+	f := o.cli.commandLine.Lookup(name)
+	if f == nil {
+		return "", false
+	}
+
+	b, ok := f.Value.(*boundFlag)
+	if !ok || !b.set {
+		return "", false
+	}
+
+	return b.String(), true
+}
+
+func (o *options) getEnv(name string) (string, bool) {
+	if o.lookupEnv != nil {
+		return o.lookupEnv(name)
+	}
+
+	return os.LookupEnv(name)
+}
+
+// environment returns all environment variables in key=value form
+// returns nil if only a lookup function was supplied, as the variables cannot be listed
+func (o *options) environment() []string {
+	if o.environ != nil {
+		return o.environ()
+	}
+
+	if o.lookupEnv != nil {
+		return nil
+	}
+
+	return os.Environ()
+}
+
+// markSet records that a source set the field at path
+func (o *options) markSet(path []string) {
+	if o.fieldsSet == nil {
+		o.fieldsSet = map[string]bool{}
+	}
+
+	o.fieldsSet[strings.Join(path, ".")] = true
+}
+
+func (o *options) warn(err error) {
+	logger.Warn("confy issued warning", "err", err.Error())
+	o.warnings = append(o.warnings, err)
 }
 
 var (
@@ -75,25 +210,43 @@ func init() {
 //
 // Sources:
 //   - CLI using the "flag" package
-//   - Environment Variables using os.Getenv(...)
+//   - Environment Variables using os.LookupEnv(...), or WithEnviron/WithLookupEnv
 //   - Configuration File using filepath or raw bytes, this supports yaml, json and toml so your configuration can file can be any of those types
 //
 // Tags
-// Confy defines two flags:
+// Confy defines these tags:
 //
 //   - confy:"field_name;sensitive"
 //     The "confy" tag is used to rename the field, meaning changing what env variables, cli flags and configuration file/bytes fields to look for
+//     Modifiers: "sensitive" masks the value in logs and help, "nonegate" stops a bool field getting a -no-<flag> counterpart,
+//     "required" makes Config return an error if no source set the field, "path" marks the field as a file path for shell completion,
+//     "static" stops Watch changing the field on reload (see WithStaticPolicy)
+//
+//   - confy_oneof:"debug,info,warn"
+//     Restricts the field (or each element of a slice) to the listed values, these are shown in the help output
 //
 //   - confy_description:"Field Description here"
 //     Sets the description of a field when being added to cli parsing, so when using -confy-help (or entering an invalid flag) it will so a good description
 //
+//   - confy_alias:"old_name,older_name;deprecated"
+//     Alternative names for the field that are still accepted from env variables, cli flags and config files. If "deprecated" is set using an alias adds a warning naming the replacement
+//     Setting both the field and one of its aliases is an error
+//
+//   - confy_cmd:"serve"
+//     Marks a struct field as a command, so "app -global-flag serve -port 80" selects it. Only the selected commands fields are populated from any source
+//     and its env variables/config file section are scoped by the command name (serve_port, serve: ...). Use WithSelectedCommand to find out which was chosen
+//
+//   - confy_arg:"0", confy_args:"rest"
+//     Populates the field from the positional argument at the index, or for slices with confy_args all arguments after the highest confy_arg index
+//     Use WithRemainingArgs to get the arguments that were not bound
+//
 // Important Note:
 //
 //	Configuring from Envs or CLI flags is more difficult for complex types (like structures)
 //	As such to unmarshal very complex structs, or structs with private members, the struct must implement encoding.TextUnmarshaler and encoding.TextMarshaler to work properly
 //
 // CLI
-// Confy will automatically register flags from the configuration file structure and parse os.Args when FromCli is supplied as an option (or defaults are used)
+// Confy will automatically register flags from the configuration file structure and parse os.Args (or WithArgs) when FromCli is supplied as an option (or defaults are used)
 // flags will be in the following format:
 //
 //	 struct {
@@ -120,23 +273,33 @@ func init() {
 //	 Thing
 //	 Nested_NestedField
 func Config[T any](suppliedOptions ...OptionFunc) (result T, warnings []error, err error) {
+	result, warnings, _, err = load[T](context.Background(), suppliedOptions)
+	return
+}
+
+// load runs the source pipeline for Config, also returning the applied options so that Watch knows what to watch
+// ctx stops requests made by the sources, e.g the retries of FromConfigURL
+func load[T any](ctx context.Context, suppliedOptions []OptionFunc) (result T, warnings []error, o *options, err error) {
 	if reflect.TypeOf(result).Kind() != reflect.Struct {
 		panic("Config(...) only supports configs of Struct type")
 	}
 
-	o := options{
+	o = &options{
 		currentlySet: make(map[preference]bool),
+		ctx:          ctx,
 	}
+	o.commands.all = getCommands(reflect.TypeOf(result), nil)
 
+	cliLoader := newCliLoader[T](o)
 	orderLoadOpts := map[preference]loader[T]{
-		cli:        newCliLoader[T](&o),
-		env:        newEnvLoader[T](&o),
-		configFile: newConfigLoader[T](&o),
+		cli:        cliLoader,
+		env:        newEnvLoader[T](o),
+		configFile: newConfigLoader[T](o),
 	}
 
 	var errs []error
 	for _, optFunc := range suppliedOptions {
-		err := optFunc(&o)
+		err := optFunc(o)
 		if err != nil {
 			errs = append(errs, err)
 		}
@@ -148,22 +311,32 @@ func Config[T any](suppliedOptions ...OptionFunc) (result T, warnings []error, e
 		if errors.Is(cErr, flag.ErrHelp) && slices.Contains(o.order, cli) {
 			orderLoadOpts[cli].apply(&result)
 		}
-		return result, nil, cErr
+		return result, nil, o, cErr
 	}
 
 	if len(o.order) == 0 {
-		if err := Defaults("config", "config.json")(&o); err != nil {
+		if err := Defaults("config", "config.json")(o); err != nil {
 			if errors.Is(err, flag.ErrHelp) && slices.Contains(o.order, cli) {
 				orderLoadOpts[cli].apply(&result)
 			}
 
-			return result, nil, err
+			return result, nil, o, err
 		}
 	}
 
 	logger.Info("Populating configuration in this order: ", slog.Any("order", o.order))
 
-	anythingWasSet := false
+	if slices.Contains(o.order, cli) {
+		// the cli is parsed up front so that the other sources know which commands were selected
+		// any error is returned when the cli source is applied in order
+		cliLoader.parse(&result)
+	}
+
+	if o.commands.result != nil {
+		*o.commands.result = o.commands.selectedName()
+	}
+
+	anythingWasSet, configDecoded := false, false
 	for _, p := range o.order {
 
 		f, ok := orderLoadOpts[p]
@@ -172,10 +345,14 @@ func Config[T any](suppliedOptions ...OptionFunc) (result T, warnings []error, e
 		}
 
 		somethingWasSet, err := f.apply(&result)
+		if p == configFile && err == nil {
+			configDecoded = true
+		}
+
 		if err != nil {
 
 			if errors.Is(err, errFatal) {
-				return result, nil, err
+				return result, nil, o, err
 			}
 
 			if len(o.order) > 1 && !errors.Is(err, flag.ErrHelp) {
@@ -184,7 +361,7 @@ func Config[T any](suppliedOptions ...OptionFunc) (result T, warnings []error, e
 				warnings = append(warnings, err)
 			} else {
 				logger.Error("parser issued error", "parser", p, "err", err.Error())
-				return result, nil, err
+				return result, nil, o, err
 			}
 		}
 
@@ -194,8 +371,33 @@ func Config[T any](suppliedOptions ...OptionFunc) (result T, warnings []error, e
 
 	}
 
+	warnings = append(warnings, o.warnings...)
+
+	if format := o.printConfigFormat(); format != "" {
+		output, err := marshal(o, result, ConfigType(strings.ToLower(format)))
+		if err != nil {
+			return result, warnings, o, fmt.Errorf("failed to print config: %w", err)
+		}
+
+		if _, err := o.flagSet().Output().Write(output); err != nil {
+			return result, warnings, o, fmt.Errorf("failed to print config: %w", err)
+		}
+
+		return result, warnings, o, ErrConfigPrinted
+	}
+
 	if !anythingWasSet {
-		return result, warnings, fmt.Errorf("nothing was set in configuration from sources: %s, warnings: %v", o.order, errors.Join(warnings...))
+		return result, warnings, o, fmt.Errorf("nothing was set in configuration from sources: %s, warnings: %v", o.order, errors.Join(warnings...))
+	}
+
+	if err := o.validate(&result); err != nil {
+		return result, warnings, o, err
+	}
+
+	if configDecoded && o.config.loaded != nil {
+		if err := o.config.loaded(); err != nil {
+			warnings = append(warnings, err)
+		}
 	}
 
 	return
@@ -280,34 +482,12 @@ func FromConfigFile(path string, configType ConfigType) OptionFunc {
 		}
 		c.currentlySet[configFile] = true
 
-		c.config.dataMethod = func() (io.Reader, ConfigType, error) {
-			configData, err := os.Open(path)
-			if err != nil {
-				return nil, "", err
-			}
-
-			var fileType ConfigType
-			if configType == Auto {
-				ext := strings.ToLower(filepath.Ext(path))
-				switch ext {
-				case ".yml", ".yaml":
-					logger.Info("yaml chosen as config type", "file_path", path)
-
-					fileType = Yaml
-				case ".json", ".js":
-					logger.Info("json chosen as config type", "file_path", path)
-
-					fileType = Json
-				case ".toml", ".tml":
-					logger.Info("toml chosen as config type", "file_path", path)
-
-					fileType = Toml
-				default:
-					return nil, "", fmt.Errorf("unsupported file extension %q", strings.ToLower(filepath.Ext(path)))
-				}
-			}
+		c.config.filePath = func() string {
+			return path
+		}
 
-			return configData, fileType, err
+		c.config.dataMethod = func() (io.Reader, ConfigType, error) {
+			return openConfigFile(c.config.filePath(), configType)
 		}
 
 		c.order = append(c.order, configFile)
@@ -316,6 +496,36 @@ func FromConfigFile(path string, configType ConfigType) OptionFunc {
 	}
 }
 
+func openConfigFile(path string, configType ConfigType) (io.Reader, ConfigType, error) {
+	fileType := configType
+	if configType == Auto {
+		ext := strings.ToLower(filepath.Ext(path))
+		switch ext {
+		case ".yml", ".yaml":
+			logger.Info("yaml chosen as config type", "file_path", path)
+
+			fileType = Yaml
+		case ".json", ".js":
+			logger.Info("json chosen as config type", "file_path", path)
+
+			fileType = Json
+		case ".toml", ".tml":
+			logger.Info("toml chosen as config type", "file_path", path)
+
+			fileType = Toml
+		default:
+			return nil, "", fmt.Errorf("unsupported file extension %q", strings.ToLower(filepath.Ext(path)))
+		}
+	}
+
+	configData, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return configData, fileType, nil
+}
+
 // FromConfigBytes tells confy to load a config file from raw bytes
 // data: []byte config file raw bytes
 // configType: ConfigType, what type the config bytes are supports yaml, toml and json, the Auto configuration will return an error
@@ -351,70 +561,22 @@ func FromConfigBytes(data []byte, configType ConfigType) OptionFunc {
 // FromConfigURL tells confy to load file from a url (http/https)
 // url: string url of configuration file
 // configType: ConfigType, what type the config file is expected to be, use `Auto` if you dont care and just want it to choose for you. Supports yaml, toml and json
+// Failed requests are retried (see WithURLRetries), and the last response that loaded successfully is kept so that reloads make conditional requests. WithURLCache keeps it on disk as a fallback for when the server is unreachable
 func FromConfigURL(urlOpt string, configType ConfigType) OptionFunc {
+	remote := &remoteConfig{
+		url:        urlOpt,
+		configType: configType,
+	}
+
 	return func(c *options) error {
 		if c.currentlySet[configFile] {
 			return errors.New("duplicate configuration information source, " + string(configFile) + " FromConfig* option set twice, mutually exclusive")
 		}
 		c.currentlySet[configFile] = true
 
+		c.config.url = remote
 		c.config.dataMethod = func() (io.Reader, ConfigType, error) {
-
-			u, err := url.Parse(urlOpt)
-			if err != nil {
-				return nil, configType, err
-			}
-
-			client := http.Client{
-				Timeout: 20 * time.Second,
-			}
-
-			resp, err := client.Get(urlOpt)
-			if err != nil {
-				return nil, configType, fmt.Errorf("failed to get config from url: %s, err: %s", urlOpt, err)
-			}
-
-			if resp.StatusCode < 200 || resp.StatusCode > 299 {
-				resp.Body.Close()
-				return nil, configType, fmt.Errorf("status code was not okay: %s", resp.Status)
-			}
-
-			var fileType ConfigType
-			if configType == Auto {
-				ext := strings.ToLower(filepath.Ext(u.Path))
-				switch ext {
-				case ".yml", ".yaml":
-					logger.Info("yaml chosen as config type from extension", "url_path", u.Path)
-
-					fileType = Yaml
-				case ".json", ".js":
-					logger.Info("json chosen as config type from extension", "url_path", u.Path)
-
-					fileType = Json
-				case ".toml", ".tml":
-					logger.Info("toml chosen as config type from extension", "url_path", u.Path)
-
-					fileType = Toml
-				default:
-					logger.Info("no extension in url, using content type instead")
-					contentType := resp.Header.Get("content-type")
-					switch contentType {
-					case "application/yaml", "application/x-yaml", "text/yaml":
-						fileType = Yaml
-					case "application/json":
-						fileType = Json
-					case "text/x-toml", "application/toml", "text/toml":
-						fileType = Toml
-					default:
-						resp.Body.Close()
-						return nil, configType, fmt.Errorf("could not automatically determine config format from extension %q or content-type %q", ext, contentType)
-
-					}
-
-				}
-			}
-
-			return resp.Body, fileType, err
+			return remote.load(c)
 		}
 
 		c.order = append(c.order, configFile)
@@ -428,21 +590,30 @@ func FromConfigURL(urlOpt string, configType ConfigType) OptionFunc {
 func FromConfigFileFlagPath(cliFlagName, defaultPath, description string, configType ConfigType) OptionFunc {
 	return func(c *options) error {
 
-		if c.cli.commandLine == nil {
-			c.cli.commandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+		// the flag itself is registered by the cli source, so that it ends up on the flag set given by WithFlagSet regardless of option order
+		c.config.pathFlag = cliFlagName
+		c.config.pathFlagDefault = defaultPath
+		c.config.pathFlagDescription = description
+
+		if err := FromConfigFile(defaultPath, configType)(c); err != nil {
+			return err
 		}
 
-		configPath := c.cli.commandLine.String(cliFlagName, defaultPath, description)
-		if err := c.cli.commandLine.Parse(os.Args[1:]); err != nil {
-			if errors.Is(err, flag.ErrHelp) {
-				return flag.ErrHelp
+		// the path is only resolved when the config is loaded, as other options (e.g WithArgs) may change where the args come from
+		c.config.filePath = func() string {
+			path, ok := c.boundFlagValue(cliFlagName)
+			if !ok {
+				path, ok = scanFlagValue(c.arguments(), cliFlagName)
+			}
+			if !ok {
+				path = defaultPath
 			}
 
-			// We will get a lot of random "flag not defined" errors,as our flags are defined much later (if at all) in the Cli component
-			configPath = &defaultPath
+			logger.Info("config path", "flag", cliFlagName, "set_by_flag", ok, "path", path)
+			return path
 		}
 
-		return FromConfigFile(*configPath, configType)(c)
+		return nil
 	}
 }
 
@@ -460,6 +631,16 @@ func WithStrictParsing() OptionFunc {
 	}
 }
 
+// WithCaseInsensitiveKeys matches config file keys (json, yaml and toml alike) and environment variable names regardless of case
+// If two keys or environment variables collide after case folding a warning is returned, and an exact match is preferred
+func WithCaseInsensitiveKeys() OptionFunc {
+	return func(c *options) error {
+		c.config.caseInsensitive = true
+		c.env.caseInsensitive = true
+		return nil
+	}
+}
+
 // WithConfigRequired causes failure to load the configuration from file/bytes/url to become fatal rather than just warning
 func WithConfigRequired() OptionFunc {
 	return func(c *options) error {
@@ -517,6 +698,43 @@ func WithEnvTransform(t Transform) OptionFunc {
 	}
 }
 
+// WithEnvPrefix namespaces all generated environment variable names with prefix, joined by the env delimiter
+// The prefix is added before any transform set by WithEnvTransform is run
+//
+// E.g
+//
+//	WithEnvPrefix("MYAPP")
+//
+// results in Database.Host being looked up as MYAPP_Database_Host
+func WithEnvPrefix(prefix string) OptionFunc {
+	return func(c *options) error {
+		if prefix == "" {
+			logger.Warn("WithEnvPrefix was used, but prefix was empty")
+		}
+
+		c.env.prefix = prefix
+		return nil
+	}
+}
+
+// WithNaming sets how generated env variables, cli flags and config file keys are named
+// Field names (and confy tag names for env/cli) are split in to words, with acronyms kept together, and then joined according to the strategy
+// Explicitly set confy:"name" tags are used as is for config file keys
+//
+// E.g
+//
+//	WithNaming(Naming{Env: ScreamingSnake, Cli: KebabCase, File: SnakeCase})
+//
+// results in Database.MaxConns being looked up as DATABASE_MAX_CONNS, -database-max-conns and database.max_conns in the config file
+func WithNaming(naming Naming) OptionFunc {
+	return func(c *options) error {
+		c.env.naming = naming.Env
+		c.cli.naming = naming.Cli
+		c.config.naming = naming.File
+		return nil
+	}
+}
+
 // FromCli will automatically look for configuration variables from CLI flags
 // delimiter: string when looking for cli flags this string should be used for denoting nested structures
 // e.g
@@ -538,9 +756,7 @@ func WithEnvTransform(t Transform) OptionFunc {
 func FromCli(delimiter string) OptionFunc {
 	return func(c *options) error {
 
-		if c.cli.commandLine == nil {
-			c.cli.commandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
-		}
+		c.flagSet()
 
 		c.cli.delimiter = delimiter
 		c.order = append(c.order, cli)
@@ -548,6 +764,159 @@ func FromCli(delimiter string) OptionFunc {
 	}
 }
 
+// WithGNUFlags parses cli flags in the GNU style instead of the go "flag" package style
+// Generated flag names default to kebab case (--database-port) unless another naming strategy is set with WithNaming
+//
+// Supports:
+//
+//	--database-port 80, --database-port=80
+//	-p 80, -p80 when the field has the confy_short:"p" tag
+//	-vq for bundled short booleans
+//	-- to stop parsing flags
+func WithGNUFlags() OptionFunc {
+	return func(c *options) error {
+		c.cli.gnu = true
+		return nil
+	}
+}
+
+// WithArgs sets the cli arguments to parse instead of os.Args, args should not include the program name
+// This is used by the cli source and FromConfigFileFlagPath
+func WithArgs(args []string) OptionFunc {
+	return func(c *options) error {
+		c.args = args
+		c.argsSet = true
+		return nil
+	}
+}
+
+// WithPrintConfigFlag adds a cli flag (e.g -print-config=yaml) that makes Config write the merged configuration to the flag set's output (stdout unless WithFlagSet is used) as json, yaml or toml using Marshal
+// When the flag is used Config returns ErrConfigPrinted, so that the program can exit rather than run
+func WithPrintConfigFlag(name string) OptionFunc {
+	return func(c *options) error {
+		if name == "" {
+			return errors.New("print config flag name must not be empty")
+		}
+
+		c.cli.printFlag = name
+		return nil
+	}
+}
+
+// printConfigFormat returns the value of the WithPrintConfigFlag flag, empty if it was not set
+func (o *options) printConfigFormat() string {
+	if o.cli.printFlag == "" {
+		return ""
+	}
+
+	if value, ok := o.boundFlagValue(o.cli.printFlag); ok {
+		return value
+	}
+
+	if o.cli.commandLine == nil {
+		return ""
+	}
+
+	f := o.cli.commandLine.Lookup(o.cli.printFlag)
+	if f == nil {
+		return ""
+	}
+
+	return f.Value.String()
+}
+
+// WithFlagSet registers and parses the cli flags on fs instead of a flag set created by confy, so that hand written flags on fs are parsed alongside confys
+// If fs was already set up by RegisterFlags and parsed by the caller, confy uses the values that were set instead of parsing it again
+// Config should only be called once per flag set, as flags cannot be registered twice
+func WithFlagSet(fs *flag.FlagSet) OptionFunc {
+	return func(c *options) error {
+		if fs == nil {
+			return errors.New("flag set must not be nil")
+		}
+
+		c.cli.commandLine = fs
+		c.cli.external = true
+		return nil
+	}
+}
+
+// WithEnviron sets the environment variables to use instead of the process environment
+// environ: either a []string of key=value pairs (as returned by os.Environ) or a map[string]string of key -> value
+func WithEnviron[E []string | map[string]string](environ E) OptionFunc {
+	return func(c *options) error {
+		values := map[string]string{}
+
+		switch e := any(environ).(type) {
+		case []string:
+			for _, kv := range e {
+				key, value, ok := strings.Cut(kv, "=")
+				if !ok {
+					return fmt.Errorf("environment variable %q is not in key=value form", kv)
+				}
+				values[key] = value
+			}
+		case map[string]string:
+			maps.Copy(values, e)
+		}
+
+		c.lookupEnv = func(name string) (string, bool) {
+			value, ok := values[name]
+			return value, ok
+		}
+
+		c.environ = func() []string {
+			var result []string
+			for key, value := range values {
+				result = append(result, key+"="+value)
+			}
+			return result
+		}
+
+		return nil
+	}
+}
+
+// WithLookupEnv sets the function used to look up environment variables instead of os.LookupEnv
+// As the variables cannot be listed, WithCaseInsensitiveKeys will only match environment variables exactly
+func WithLookupEnv(lookup func(name string) (string, bool)) OptionFunc {
+	return func(c *options) error {
+		if lookup == nil {
+			return errors.New("WithLookupEnv was used, but lookup was nil")
+		}
+
+		c.lookupEnv = lookup
+		c.environ = nil
+		return nil
+	}
+}
+
+// WithSelectedCommand sets selected to the command chosen on the command line, e.g "serve" or "db migrate" for nested commands
+// selected is set to "" if no command was chosen
+//
+// Commands are struct fields with the confy_cmd:"name" tag, only the selected commands fields (and top level fields) are populated by any source
+func WithSelectedCommand(selected *string) OptionFunc {
+	return func(c *options) error {
+		if selected == nil {
+			return errors.New("WithSelectedCommand was used, but selected was nil")
+		}
+
+		c.commands.result = selected
+		return nil
+	}
+}
+
+// WithRemainingArgs sets remaining to the positional cli arguments that were not bound to a confy_arg or confy_args field
+func WithRemainingArgs(remaining *[]string) OptionFunc {
+	return func(c *options) error {
+		if remaining == nil {
+			return errors.New("WithRemainingArgs was used, but remaining was nil")
+		}
+
+		c.cli.remaining = remaining
+		return nil
+	}
+}
+
 // WithCliTransform runs the auto generated cli flag name through function t(generated string)string
 // allowing you to change the flag name if required
 //
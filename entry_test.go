@@ -53,3 +53,68 @@ func TestConfigurationHelp(t *testing.T) {
 	}
 
 }
+
+func TestInjectedArgsAndEnviron(t *testing.T) {
+
+	type injected struct {
+		Host string
+		Port int
+	}
+
+	for i := 0; i < 4; i++ {
+		t.Run("parallel", func(t *testing.T) {
+			t.Parallel()
+
+			config, _, err := Config[injected](
+				FromEnvs(ENVDelimiter),
+				FromCli(CLIDelimiter),
+				WithArgs([]string{"-Port", "80"}),
+				WithEnviron(map[string]string{"Host": "map_host"}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if config.Host != "map_host" || config.Port != 80 {
+				t.Fatalf("%+v", config)
+			}
+		})
+	}
+
+	config, _, err := Config[injected](FromEnvs(ENVDelimiter), WithEnviron([]string{"Host=slice_host", "Port=22"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Host != "slice_host" || config.Port != 22 {
+		t.Fatalf("%+v", config)
+	}
+
+	config, _, err = Config[injected](FromEnvs(ENVDelimiter), WithLookupEnv(func(name string) (string, bool) {
+		return "lookup_" + name, name == "Host"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Host != "lookup_Host" {
+		t.Fatalf("%+v", config)
+	}
+}
+
+func TestInjectedArgsConfigFlagPath(t *testing.T) {
+	os.Args = []string{"dummy", "-config", "does_not_exist.json"}
+
+	config, _, err := Config[testStruct](
+		FromConfigFileFlagPath("config", "config.json", "config file path", Auto),
+		WithArgs([]string{"--config=testdata/test.yaml"}),
+		WithConfigRequired(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Thing != "example_string" {
+		t.Fatalf("%+v", config)
+	}
+}
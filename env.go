@@ -2,8 +2,10 @@ package confy
 
 import (
 	"encoding"
-	"os"
+	"errors"
+	"fmt"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -47,22 +49,39 @@ func GetGeneratedEnv[T any](delimiter string) []string {
 		panic("GetGeneratedEnv(...) only supports configs of Struct type")
 	}
 
-	o := options{}
-	FromEnvs(delimiter)(&o)
-	ep := newEnvLoader[T](&o)
+	// FromEnvs cannot fail, so neither can this
+	result, _ := GetGeneratedEnvWithOptions[T](FromEnvs(delimiter))
+	return result
+}
 
-	var result []string
-	for _, field := range getFields(true, &a) {
+// GetGeneratedEnvWithOptions return list of auto generated environment variable names that Config will check when given the same options
+// this takes into account the delimiter, prefix and transform that the options set
+func GetGeneratedEnvWithOptions[T any](suppliedOptions ...OptionFunc) ([]string, error) {
+	var a T
+	if reflect.TypeOf(a).Kind() != reflect.Struct {
+		panic("GetGeneratedEnvWithOptions(...) only supports configs of Struct type")
+	}
 
-		envVariable, ok := determineVariableName(&a, ep.o.env.delimiter, nil, field)
-		if !ok {
-			continue
+	o := options{
+		currentlySet: make(map[preference]bool),
+	}
+
+	var errs []error
+	for _, optFunc := range suppliedOptions {
+		if err := optFunc(&o); err != nil {
+			errs = append(errs, err)
 		}
+	}
 
-		result = append(result, envVariable)
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
 
-	return result
+	if o.env.delimiter == "" {
+		o.env.delimiter = ENVDelimiter
+	}
+
+	return newEnvLoader[T](&o).generatedNames(&a), nil
 }
 
 // GetGeneratedEnvWithTransform return list of auto generated environment variable names that LoadEnv/Config will check
@@ -83,20 +102,105 @@ func GetGeneratedEnvWithTransform[T any](delimiter string, transformFunc Transfo
 	return envs
 }
 
+func (ep *envParser[T]) generatedNames(result *T) []string {
+	var names []string
+	for _, field := range getFields(true, result) {
+		envVariable, ok := determineVariableName(result, ep.o.env, field)
+		if !ok {
+			continue
+		}
+
+		names = append(names, envVariable)
+	}
+
+	return names
+}
+
+// foldedEnviron returns all environment variable names grouped by their lower case form
+func (ep *envParser[T]) foldedEnviron() map[string][]string {
+	folded := map[string][]string{}
+	for _, kv := range ep.o.environment() {
+		name, _, _ := strings.Cut(kv, "=")
+		folded[strings.ToLower(name)] = append(folded[strings.ToLower(name)], name)
+	}
+
+	for k := range folded {
+		slices.Sort(folded[k])
+	}
+
+	return folded
+}
+
+// lookup finds the environment variable name, if folded is not nil the name is matched case insensitively
+// preferring an exact match
+func (ep *envParser[T]) lookup(name string, folded map[string][]string) (string, bool) {
+	value, ok := ep.o.getEnv(name)
+	if folded == nil {
+		return value, ok
+	}
+
+	candidates := folded[strings.ToLower(name)]
+	if len(candidates) == 0 {
+		return value, ok
+	}
+
+	chosen := candidates[0]
+	if ok {
+		chosen = name
+	}
+
+	if len(candidates) > 1 {
+		ep.o.warn(fmt.Errorf("environment variables %v collide after case folding for %q, using %q", candidates, name, chosen))
+	}
+
+	return ep.o.getEnv(chosen)
+}
+
 func (ep *envParser[T]) apply(result *T) (somethingSet bool, err error) {
 
+	var folded map[string][]string
+	if ep.o.env.caseInsensitive {
+		folded = ep.foldedEnviron()
+	}
+
 	for _, field := range getFields(true, result) {
-		envVariable, ok := determineVariableName(result, ep.o.env.delimiter, ep.o.env.transform, field)
+		if ep.o.commands.inactive(field.path) {
+			continue
+		}
+
+		envVariable, ok := determineVariableName(result, ep.o.env, field)
 		if !ok {
 			continue
 		}
 
-		value, wasSet := os.LookupEnv(envVariable)
+		value, wasSet := ep.lookup(envVariable, folded)
 		logger.Info("ENV", "was_set", wasSet, envVariable, maskSensitive(value, field.tag))
 
+		setBy := envVariable
+		aliases, deprecated := determineAliasNames(result, ep.o.env, field, 0)
+		for _, alias := range aliases {
+			aliasValue, aliasSet := ep.lookup(alias, folded)
+			if !aliasSet {
+				continue
+			}
+
+			if wasSet {
+				return somethingSet, fmt.Errorf("%w: environment variables %q and %q are both set for the same option, only one may be used", errFatal, setBy, alias)
+			}
+
+			logger.Info("ENV (alias)", "alias_of", envVariable, alias, maskSensitive(aliasValue, field.tag))
+
+			if deprecated {
+				ep.o.warn(fmt.Errorf("environment variable %q is deprecated, use %q instead", alias, envVariable))
+			}
+
+			value, wasSet, setBy = aliasValue, true, alias
+		}
+
 		if wasSet {
 			somethingSet = true
 			ep.setBasicFieldFromString(result, field.path, value)
+			ep.o.markSet(field.path)
 		}
 	}
 
@@ -131,13 +235,12 @@ outer:
 					}
 					f.SetInt(int64(reflectedVal))
 				case reflect.Bool:
-					switch value {
-					case "true", "false", "":
-						f.SetBool(value == "true" && value != "")
-					default:
+					b, err := parseBool(value)
+					if err != nil {
 						logger.Error("field should be bool", "value", value, "path", flagName)
 						continue
 					}
+					f.SetBool(b)
 				case reflect.Float64:
 					if isBlank {
 						f.SetFloat(0)
@@ -190,13 +293,12 @@ outer:
 						var resultingArray []bool
 						for _, p := range sliceParts {
 
-							switch p {
-							case "true", "false":
-								resultingArray = append(resultingArray, p == "true")
-							default:
+							b, err := parseBool(p)
+							if err != nil || p == "" {
 								logger.Error("expected bool could not parse", "value", p, "path", flagName)
 								continue outer
 							}
+							resultingArray = append(resultingArray, b)
 						}
 
 						f.Set(reflect.ValueOf(resultingArray))
@@ -256,3 +358,15 @@ outer:
 		}
 	}
 }
+
+// parseBool accepts true/false, yes/no, on/off and 1/0 (case insensitive), a blank value is false
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "on", "1", "t", "y":
+		return true, nil
+	case "false", "no", "off", "0", "f", "n", "":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid boolean value %q, expected true/false, yes/no, on/off or 1/0", value)
+	}
+}
@@ -151,3 +151,130 @@ func TestEnvTransform(t *testing.T) {
 		}
 	}
 }
+
+func TestEnvPrefix(t *testing.T) {
+
+	t.Setenv("MYAPP_thing", "prefixed")
+	t.Setenv("MYAPP_thonku_complex_Mff", "prefixed_inner")
+
+	dummyConfig, _, err := Config[testStruct](FromEnvs(ENVDelimiter), WithEnvPrefix("MYAPP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dummyConfig.Thing != "prefixed" {
+		t.Fatalf("%+v", dummyConfig)
+	}
+
+	if dummyConfig.Thonku.Mff != "prefixed_inner" {
+		t.Fatalf("%+v", dummyConfig)
+	}
+}
+
+func TestEnvHelperMethodWithOptions(t *testing.T) {
+	type Small struct {
+		Thing  string
+		Nested struct {
+			NestedVal string
+		}
+	}
+
+	expectedContents := []string{
+		"MYAPP_THING",
+		"MYAPP_NESTED_NESTEDVAL",
+	}
+
+	vals, err := GetGeneratedEnvWithOptions[Small](FromEnvs(ENVDelimiter), WithEnvPrefix("myapp"), WithEnvTransform(strings.ToUpper))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expectedContents, vals) {
+		t.Fatalf("expected %v got %v", expectedContents, vals)
+	}
+}
+
+func TestEnvCaseInsensitive(t *testing.T) {
+
+	t.Setenv("CASE_INSENSITIVE_THING", "folded")
+
+	type caseStruct struct {
+		Case_Insensitive_Thing string
+	}
+
+	dummyConfig, _, err := Config[caseStruct](FromEnvs(ENVDelimiter), WithCaseInsensitiveKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dummyConfig.Case_Insensitive_Thing != "folded" {
+		t.Fatalf("%+v", dummyConfig)
+	}
+
+	t.Setenv("case_insensitive_thing", "other")
+	_, warnings, err := Config[caseStruct](FromEnvs(ENVDelimiter), WithCaseInsensitiveKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("expected collision warning got %v", warnings)
+	}
+}
+
+func TestEnvAliases(t *testing.T) {
+
+	os.Setenv("database_hostname", "old_host")
+
+	dummyConfig, warnings, err := Config[aliasStruct](FromEnvs(ENVDelimiter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dummyConfig.Database.Host != "old_host" {
+		t.Fatalf("%+v", dummyConfig)
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("expected deprecation warning got %v", warnings)
+	}
+
+	os.Setenv("database_host", "new_host")
+	defer os.Unsetenv("database_host")
+	defer os.Unsetenv("database_hostname")
+
+	_, _, err = Config[aliasStruct](FromEnvs(ENVDelimiter))
+	if err == nil {
+		t.Fatal("expected setting both the alias and the new name to be an error")
+	}
+}
+
+func TestEnvBoolValues(t *testing.T) {
+
+	type bools struct {
+		On    bool
+		Yes   bool
+		One   bool
+		Off   bool
+		Slice []bool
+	}
+
+	t.Setenv("On", "on")
+	t.Setenv("Yes", "YES")
+	t.Setenv("One", "1")
+	t.Setenv("Off", "off")
+	t.Setenv("Slice", "yes,no,1,0")
+
+	dummyConfig, err := LoadEnv[bools](ENVDelimiter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !dummyConfig.On || !dummyConfig.Yes || !dummyConfig.One || dummyConfig.Off {
+		t.Fatalf("%+v", dummyConfig)
+	}
+
+	if !reflect.DeepEqual(dummyConfig.Slice, []bool{true, false, true, false}) {
+		t.Fatalf("%+v", dummyConfig.Slice)
+	}
+}
@@ -0,0 +1,177 @@
+package confy
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(boolFlag)
+	return ok && b.IsBoolFlag()
+}
+
+// gnuParser parses arguments in the GNU style, --long-flag, --long-flag=value, -s, bundled short booleans -vq and -- to terminate flag parsing
+// the flags themselves are still held by a flag.FlagSet so that Visit/Lookup work as normal after parsing
+type gnuParser struct {
+	fs *flag.FlagSet
+
+	// short single letter name -> long flag name
+	shorts map[string]string
+
+	// stop parsing flags at the first positional argument, rather than allowing flags and arguments to be mixed
+	stopAtPositional bool
+}
+
+func newGnuParser(fs *flag.FlagSet) *gnuParser {
+	return &gnuParser{
+		fs:     fs,
+		shorts: map[string]string{},
+	}
+}
+
+func (g *gnuParser) addShort(short, long string) error {
+	if utf8.RuneCountInString(short) != 1 {
+		return fmt.Errorf("short flag %q for --%s must be a single character", short, long)
+	}
+
+	if existing, ok := g.shorts[short]; ok {
+		return fmt.Errorf("short flag -%s is used by both --%s and --%s", short, existing, long)
+	}
+
+	g.shorts[short] = long
+	return nil
+}
+
+// isNegativeNumber reports whether arg is a number such as -5 or -1.5 rather than a cluster of short flags, unless a digit has been given as a short flag
+func (g *gnuParser) isNegativeNumber(arg string) bool {
+	if c := arg[1]; (c < '0' || c > '9') && c != '.' {
+		return false
+	}
+
+	if _, ok := g.shorts[arg[1:2]]; ok {
+		return false
+	}
+
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+// parse sets the flags within args and returns the positional (non-flag) arguments
+func (g *gnuParser) parse(args []string) ([]string, error) {
+	positional, err := g.parseArgs(args)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(g.fs.Output(), err)
+		}
+		if g.fs.Usage != nil {
+			g.fs.Usage()
+		}
+	}
+
+	return positional, err
+}
+
+func (g *gnuParser) parseArgs(args []string) (positional []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			return append(positional, args[i+1:]...), nil
+
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+
+			f := g.fs.Lookup(name)
+			if f == nil {
+				if name == "help" {
+					return positional, flag.ErrHelp
+				}
+				return positional, fmt.Errorf("flag provided but not defined: --%s", name)
+			}
+
+			if !hasValue {
+				if isBoolFlag(f) {
+					value = "true"
+				} else {
+					if i+1 >= len(args) {
+						return positional, fmt.Errorf("flag needs an argument: --%s", name)
+					}
+					i++
+					value = args[i]
+				}
+			}
+
+			if err := g.fs.Set(name, value); err != nil {
+				return positional, fmt.Errorf("invalid value %q for flag --%s: %w", value, name, err)
+			}
+
+		case strings.HasPrefix(arg, "-") && len(arg) > 1 && !g.isNegativeNumber(arg):
+			shorts := []rune(arg[1:])
+			for j := 0; j < len(shorts); j++ {
+				short := string(shorts[j])
+
+				name, ok := g.shorts[short]
+				if !ok {
+					if short == "h" {
+						return positional, flag.ErrHelp
+					}
+					return positional, fmt.Errorf("flag provided but not defined: -%s", short)
+				}
+
+				f := g.fs.Lookup(name)
+				if f == nil {
+					return positional, fmt.Errorf("short flag -%s refers to undefined flag --%s", short, name)
+				}
+
+				rest := string(shorts[j+1:])
+				if isBoolFlag(f) {
+					value := "true"
+					if strings.HasPrefix(rest, "=") {
+						// -v=false
+						value = rest[1:]
+						j = len(shorts)
+					}
+
+					if err := g.fs.Set(name, value); err != nil {
+						return positional, fmt.Errorf("invalid value %q for flag -%s: %w", value, short, err)
+					}
+					continue
+				}
+
+				// a non-bool short consumes the rest of the argument (-p80, -p=80) or the next argument (-p 80)
+				value := strings.TrimPrefix(rest, "=")
+				if rest == "" {
+					if i+1 >= len(args) {
+						return positional, fmt.Errorf("flag needs an argument: -%s", short)
+					}
+					i++
+					value = args[i]
+				}
+
+				if err := g.fs.Set(name, value); err != nil {
+					return positional, fmt.Errorf("invalid value %q for flag -%s: %w", value, short, err)
+				}
+				break
+			}
+
+		default:
+			if g.stopAtPositional {
+				return append(positional, args[i:]...), nil
+			}
+
+			// GNU style allows flags and positional arguments to be mixed
+			positional = append(positional, arg)
+		}
+	}
+
+	return positional, nil
+}
@@ -0,0 +1,447 @@
+package confy
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// HelpData is everything confy knows about the options of a configuration structure, it is passed to the help template
+type HelpData struct {
+	Program string
+	// Command is the selected command chain (e.g "db migrate"), empty for the top level
+	Command string
+	// Description is the confy_description of the command
+	Description string
+
+	// Sections holds the options generated from the structure, grouped by nested structure
+	Sections []HelpSection
+	// Flags are flags that were not generated from the structure, e.g the config file path flag or hand written flags on a WithFlagSet flag set
+	Flags []HelpOption
+
+	Commands  []HelpCommand
+	Arguments []HelpArgument
+}
+
+// HelpSection groups the options of a nested structure
+type HelpSection struct {
+	// Title is the path of the nested structure (e.g Database.Pool), empty for the top level options
+	Title       string
+	Description string
+
+	Options []HelpOption
+}
+
+// HelpOption describes a single option and every way it can be set
+type HelpOption struct {
+	// Flag is the cli flag including its leading dashes, e.g --database-port
+	Flag  string
+	Short string
+	// Negation is the flag that sets a bool option to false, e.g --no-verbose
+	Negation string
+
+	Aliases           []string
+	AliasesDeprecated bool
+
+	// Env is the environment variable name, empty if the env source is not used
+	Env string
+	// FileKey is the dotted path of the key in the config file, empty if the config file source is not used
+	FileKey string
+
+	Type        string
+	Default     string
+	Description string
+
+	// Allowed holds the values from confy_oneof
+	Allowed   []string
+	Required  bool
+	Sensitive bool
+	// Static is set for options with the static modifier, which Watch will not change without a restart
+	Static bool
+	// Path is set for options that hold a file path, from the path modifier or the FromConfigFileFlagPath flag
+	Path bool
+}
+
+type HelpCommand struct {
+	Name        string
+	Description string
+}
+
+type HelpArgument struct {
+	// Name is the position of the argument, e.g [0], or [...] for the remaining arguments
+	Name        string
+	Type        string
+	Description string
+}
+
+var helpFuncs = template.FuncMap{
+	"join":    strings.Join,
+	"details": helpDetails,
+}
+
+const defaultHelpTemplate = `{{define "option"}}  {{if .Short}}{{.Short}}, {{else}}    {{end}}{{.Flag}}{{with .Type}} {{.}}{{end}}
+{{- with .Description}}
+        {{.}}{{end}}
+{{- with details .}}
+        {{.}}{{end}}
+{{end -}}
+Usage: {{.Program}}{{with .Command}} {{.}}{{end}} [options]{{if .Commands}} <command>{{end}}{{range .Arguments}} {{.Name}}{{end}}
+{{- with .Description}}
+
+{{.}}{{end}}
+{{range .Sections}}
+{{with .Title}}{{.}}{{else}}Options{{end}}:{{with .Description}} {{.}}{{end}}
+{{range .Options}}{{template "option" .}}{{end}}{{end}}
+{{- if .Flags}}
+Other options:
+{{range .Flags}}{{template "option" .}}{{end}}{{end}}
+{{- if .Commands}}
+Commands:
+{{range .Commands}}  {{.Name}}{{with .Description}}
+        {{.}}{{end}}
+{{end}}{{end}}
+{{- if .Arguments}}
+Arguments:
+{{range .Arguments}}  {{.Name}}{{with .Type}} {{.}}{{end}}{{with .Description}}
+        {{.}}{{end}}
+{{end}}{{end}}`
+
+var defaultHelp = template.Must(template.New("help").Funcs(helpFuncs).Parse(defaultHelpTemplate))
+
+// WithHelpTemplate replaces the cli help output with a text/template, the template is executed with HelpData
+// Besides the standard template functions "join" (strings.Join) and "details" (the env, file key, default, allowed values etc of a HelpOption as a single line) are available
+func WithHelpTemplate(text string) OptionFunc {
+	return func(c *options) error {
+		tmpl, err := template.New("help").Funcs(helpFuncs).Parse(text)
+		if err != nil {
+			return fmt.Errorf("invalid help template: %w", err)
+		}
+
+		c.cli.helpTemplate = tmpl
+		return nil
+	}
+}
+
+// Help returns the help data for the top level of T, as it would be shown by Config with the same options
+func Help[T any](suppliedOptions ...OptionFunc) (HelpData, error) {
+	data, _, err := helpFor[T](suppliedOptions)
+	return data, err
+}
+
+// WriteHelp writes the help for the top level of T to w, using the template set by WithHelpTemplate if given
+func WriteHelp[T any](w io.Writer, suppliedOptions ...OptionFunc) error {
+	data, o, err := helpFor[T](suppliedOptions)
+	if err != nil {
+		return err
+	}
+
+	return o.helpTemplate().Execute(w, data)
+}
+
+func helpFor[T any](suppliedOptions []OptionFunc) (HelpData, *options, error) {
+	cp, err := newHelpParser[T](suppliedOptions)
+	if err != nil {
+		return HelpData{}, nil, err
+	}
+
+	data, err := cp.levelHelp(nil)
+	return data, cp.o, err
+}
+
+// newHelpParser returns a cli parser set up with the options, for describing T without parsing any arguments
+func newHelpParser[T any](suppliedOptions []OptionFunc) (*ciParser[T], error) {
+	var a T
+	if reflect.TypeOf(a).Kind() != reflect.Struct {
+		panic("Help(...) only supports configs of Struct type")
+	}
+
+	o := &options{
+		currentlySet: make(map[preference]bool),
+	}
+	o.commands.all = getCommands(reflect.TypeOf(a), nil)
+
+	var errs []error
+	for _, optFunc := range suppliedOptions {
+		if err := optFunc(o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if len(o.order) == 0 {
+		if err := Defaults("config", "config.json")(o); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.cli.delimiter == "" {
+		o.cli.delimiter = CLIDelimiter
+	}
+
+	cp := newCliLoader[T](o)
+	cp.dummy = new(T)
+
+	return cp, nil
+}
+
+// levelHelp describes the options of cmd, or the top level if cmd is nil
+func (cp *ciParser[T]) levelHelp(cmd *command) (HelpData, error) {
+	name := cp.o.programName()
+	if cmd != nil {
+		name = cmd.name
+	}
+
+	level := cp.newLevel(flag.NewFlagSet(name, flag.ContinueOnError), cmd)
+	if err := cp.registerLevel(cp.dummy, level); err != nil {
+		return HelpData{}, err
+	}
+
+	data := cp.helpData(level)
+	if cmd != nil {
+		// commands are not selected when describing them, so name them by their chain of parents
+		var chain []string
+		for _, parent := range cp.o.commands.all {
+			if isPathPrefix(parent.path, cmd.path) {
+				chain = append(chain, parent.name)
+			}
+		}
+		data.Command = strings.Join(chain, " ")
+	}
+
+	return data, nil
+}
+
+func (o *options) helpTemplate() *template.Template {
+	if o.cli.helpTemplate != nil {
+		return o.cli.helpTemplate
+	}
+
+	return defaultHelp
+}
+
+// helpDetails summarises everything about an option other than its flag and description
+func helpDetails(option HelpOption) string {
+	var details []string
+	if option.Env != "" {
+		details = append(details, "env: "+option.Env)
+	}
+
+	if option.FileKey != "" {
+		details = append(details, "file: "+option.FileKey)
+	}
+
+	if option.Default != "" {
+		details = append(details, fmt.Sprintf("default: %q", option.Default))
+	}
+
+	if len(option.Allowed) > 0 {
+		details = append(details, "one of: "+strings.Join(option.Allowed, ", "))
+	}
+
+	if option.Negation != "" {
+		details = append(details, "negate: "+option.Negation)
+	}
+
+	if len(option.Aliases) > 0 {
+		label := "aliases: "
+		if option.AliasesDeprecated {
+			label = "deprecated aliases: "
+		}
+		details = append(details, label+strings.Join(option.Aliases, ", "))
+	}
+
+	if option.Required {
+		details = append(details, "required")
+	}
+
+	if option.Sensitive {
+		details = append(details, "sensitive")
+	}
+
+	if option.Static {
+		details = append(details, "requires restart")
+	}
+
+	return strings.Join(details, ", ")
+}
+
+func helpTypeName(t reflect.Type) string {
+	switch {
+	case t == reflect.TypeOf(time.Duration(0)):
+		return "duration"
+	case t.Kind() == reflect.Slice:
+		return "[]" + helpTypeName(t.Elem())
+	case t.Kind() == reflect.Ptr:
+		return helpTypeName(t.Elem())
+	}
+
+	return t.String()
+}
+
+// fileKeyPath returns the dotted config file key for the go field path, based on the tags createModifiedType adds
+func fileKeyPath(modified reflect.Type, path []string, tagName string) string {
+	var keys []string
+	t := modified
+	for _, part := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		field, ok := t.FieldByName(part)
+		if !ok {
+			return ""
+		}
+
+		keys = append(keys, fileKey(field, tagName))
+		t = field.Type
+	}
+
+	return strings.Join(keys, ".")
+}
+
+// helpData describes the options of level
+func (cp *ciParser[T]) helpData(level *cliLevel) HelpData {
+	data := HelpData{
+		Program: cp.o.programName(),
+		Command: cp.o.commands.selectedName(),
+	}
+
+	if level.command != nil {
+		_, structField := getField(cp.dummy, level.command.path)
+		data.Description = structField.Tag.Get(confyDescriptionTag)
+	}
+
+	long, short := "-", "-"
+	if level.gnu != nil {
+		long = "--"
+	}
+
+	depth := 0
+	if level.command != nil {
+		depth = len(level.command.path)
+	}
+
+	var modified reflect.Type
+	if slices.Contains(cp.o.order, configFile) {
+		modified = newConfigLoader[T](cp.o).createModifiedType(reflect.TypeOf(*cp.dummy))
+	}
+
+	sections := map[string]int{}
+	for _, option := range level.options {
+		field := option.field
+
+		help := HelpOption{
+			Flag:        long + option.name,
+			Type:        helpTypeName(field.value.Type()),
+			Description: field.tag.Get(confyDescriptionTag),
+			Allowed:     getOneOf(field.tag),
+			Required:    hasModifier(field.tag, confyTag, "required"),
+			Sensitive:   hasModifier(field.tag, confyTag, "sensitive"),
+			Static:      hasModifier(field.tag, confyTag, "static"),
+			Path:        hasModifier(field.tag, confyTag, "path"),
+		}
+
+		if field.value.Kind() == reflect.Bool {
+			// as with the flag package, bools take no value so their type is not shown
+			help.Type = ""
+		}
+
+		if s, ok := field.tag.Lookup(confyShortTag); ok {
+			help.Short = short + s
+		}
+
+		if _, ok := level.associations["no-"+option.name]; ok {
+			help.Negation = long + "no-" + option.name
+		}
+
+		aliases, deprecated := determineAliasNames(cp.dummy, cp.naming(), field, depth)
+		for _, alias := range aliases {
+			help.Aliases = append(help.Aliases, long+alias)
+		}
+		help.AliasesDeprecated = deprecated
+
+		if slices.Contains(cp.o.order, env) {
+			help.Env, _ = determineVariableName(cp.dummy, cp.o.env, field)
+		}
+
+		if modified != nil {
+			help.FileKey = fileKeyPath(modified, field.path, "yaml")
+		}
+
+		if !field.value.IsZero() && !help.Sensitive {
+			help.Default = valueString(field.value)
+		}
+
+		parent := field.path[:len(field.path)-1]
+		title := strings.Join(parent[depth:], ".")
+		idx, ok := sections[title]
+		if !ok {
+			idx = len(data.Sections)
+			sections[title] = idx
+
+			section := HelpSection{Title: title}
+			if len(parent) > depth {
+				_, structField := getField(cp.dummy, parent)
+				section.Description = structField.Tag.Get(confyDescriptionTag)
+			}
+			data.Sections = append(data.Sections, section)
+		}
+
+		data.Sections[idx].Options = append(data.Sections[idx].Options, help)
+	}
+
+	level.fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := level.associations[f.Name]; ok || f.Name == sourceHelpFlag {
+			return
+		}
+
+		typeName, usage := flag.UnquoteUsage(f)
+		help := HelpOption{
+			Flag:        long + f.Name,
+			Type:        typeName,
+			Description: usage,
+			Path:        f.Name == cp.o.config.pathFlag,
+		}
+
+		if f.Name == cp.o.cli.printFlag {
+			help.Allowed = []string{string(Json), string(Yaml), string(Toml)}
+		}
+
+		switch f.DefValue {
+		case "", "0", "false", "[]":
+		default:
+			help.Default = f.DefValue
+		}
+
+		data.Flags = append(data.Flags, help)
+	})
+
+	for _, cmd := range cp.o.commands.children(level.command) {
+		_, structField := getField(cp.dummy, cmd.path)
+		data.Commands = append(data.Commands, HelpCommand{Name: cmd.name, Description: structField.Tag.Get(confyDescriptionTag)})
+	}
+
+	for _, positional := range level.positional {
+		name := fmt.Sprintf("[%d]", positional.index)
+		if positional.index < 0 {
+			name = "[...]"
+		}
+
+		data.Arguments = append(data.Arguments, HelpArgument{
+			Name:        name,
+			Type:        helpTypeName(positional.field.value.Type()),
+			Description: positional.field.tag.Get(confyDescriptionTag),
+		})
+	}
+
+	return data
+}
@@ -0,0 +1,83 @@
+package confy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type helpStruct struct {
+	Level    string `confy:"level" confy_oneof:"debug,info" confy_description:"log level" confy_short:"l"`
+	Verbose  bool
+	Database struct {
+		Port     int    `confy:";required" confy_description:"port to connect to"`
+		Password string `confy:"password;sensitive"`
+	} `confy_description:"database connection"`
+}
+
+func TestHelpGrouped(t *testing.T) {
+
+	data, err := Help[helpStruct](Defaults("config", "config.json"), WithGNUFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Sections) != 2 || data.Sections[0].Title != "" || data.Sections[1].Title != "Database" || data.Sections[1].Description != "database connection" {
+		t.Fatalf("expected options to be grouped by nested struct: %+v", data.Sections)
+	}
+
+	level := data.Sections[0].Options[0]
+	if level.Flag != "--level" || level.Short != "-l" || level.Env != "level" || level.FileKey != "level" || level.Type != "string" || strings.Join(level.Allowed, ",") != "debug,info" {
+		t.Fatalf("unexpected level option: %+v", level)
+	}
+
+	port := data.Sections[1].Options[0]
+	if port.Flag != "--database-port" || port.FileKey != "Database.Port" || port.Env != "Database_Port" || !port.Required {
+		t.Fatalf("unexpected port option: %+v", port)
+	}
+
+	if !data.Sections[1].Options[1].Sensitive {
+		t.Fatalf("expected password to be sensitive: %+v", data.Sections[1].Options[1])
+	}
+
+	if len(data.Flags) != 1 || data.Flags[0].Flag != "--config" || data.Flags[0].Default != "config.json" {
+		t.Fatalf("expected the config flag to be listed once: %+v", data.Flags)
+	}
+
+	var b bytes.Buffer
+	if err := WriteHelp[helpStruct](&b, Defaults("config", "config.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	output := b.String()
+	if strings.Contains(output, sourceHelpFlag) {
+		t.Fatalf("help should not list the %s flag:\n%s", sourceHelpFlag, output)
+	}
+
+	if strings.Count(output, "-config") != 1 {
+		t.Fatalf("expected -config to be listed once:\n%s", output)
+	}
+
+	for _, expected := range []string{"Database: database connection", "-Database.Port int", "env: Database_Port, file: Database.Port, required", "one of: debug, info", "negate: -no-Verbose"} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in help:\n%s", expected, output)
+		}
+	}
+}
+
+func TestHelpTemplate(t *testing.T) {
+
+	var b bytes.Buffer
+	err := WriteHelp[helpStruct](&b, FromCli(CLIDelimiter), WithHelpTemplate(`{{range .Sections}}{{range .Options}}{{.Flag}};{{end}}{{end}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.String() != "-level;-Verbose;-Database.Port;-Database.password;" {
+		t.Fatalf("unexpected custom help output: %q", b.String())
+	}
+
+	if _, err := Help[helpStruct](WithHelpTemplate(`{{.Broken`)); err == nil {
+		t.Fatal("expected invalid template to be an error")
+	}
+}
@@ -0,0 +1,288 @@
+package confy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// normalizeKeys decodes the config data in to a generic document, rewrites the keys to exactly match what the decoder for target expects
+// and then re-encodes it in the same format so the regular (strict or not) decoding can continue as normal
+func (cp *configParser[T]) normalizeKeys(configData io.Reader, configType ConfigType, target reflect.Type) (io.Reader, error) {
+	doc, tagName, err := decodeDocument(configData, configType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode config for key normalization: %s", err)
+	}
+
+	doc, err = cp.rewriteKeys(doc, target, tagName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []byte
+	switch configType {
+	case Json:
+		result, err = json.Marshal(doc)
+	case Yaml:
+		result, err = yaml.Marshal(doc)
+	case Toml:
+		result, err = toml.Marshal(doc)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-encode normalized config: %s", err)
+	}
+
+	return bytes.NewReader(result), nil
+}
+
+// decodeDocument decodes the config data in to generic maps and slices, returning the name of the struct tag the decoder for configType uses
+func decodeDocument(configData io.Reader, configType ConfigType) (doc any, tagName string, err error) {
+	switch configType {
+	case Json:
+		dec := json.NewDecoder(configData)
+		dec.UseNumber()
+		return doc, "json", dec.Decode(&doc)
+	case Yaml:
+		return doc, "yaml", yaml.NewDecoder(configData).Decode(&doc)
+	case Toml:
+		return doc, "toml", toml.NewDecoder(configData).Decode(&doc)
+	default:
+		return nil, "", fmt.Errorf("config type %q could not be determined", configType)
+	}
+}
+
+// presentFields marks the go path (e.g Database.Port) of every field of t that has a key in doc, t being the type the config is decoded in to
+// json and toml decoders match keys case insensitively, so the keys are matched the same way for them
+func presentFields(doc any, t reflect.Type, tagName string, path []string, present map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	m, ok := doc.(map[string]any)
+	if !ok || t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		key := fileKey(field, tagName)
+		if key == "-" {
+			continue
+		}
+
+		v, ok := m[key]
+		if !ok && tagName != "yaml" {
+			for k, candidate := range m {
+				if strings.EqualFold(k, key) {
+					v, ok = candidate, true
+					break
+				}
+			}
+		}
+
+		if !ok {
+			continue
+		}
+
+		fieldPath := append(slices.Clip(path), field.Name)
+		present[strings.Join(fieldPath, ".")] = true
+
+		if field.Type.Kind() == reflect.Struct {
+			presentFields(v, field.Type, tagName, fieldPath, present)
+		}
+	}
+}
+
+// rewriteKeys walks doc alongside the type t and renames keys that are aliases or case fold to an expected key
+func (cp *configParser[T]) rewriteKeys(doc any, t reflect.Type, tagName string, path []string) (any, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := doc.(map[string]any)
+		if !ok {
+			return doc, nil
+		}
+
+		expected := map[string]reflect.Type{}
+		deprecated := map[string]bool{}
+		aliasOf := map[string]string{}
+		folded := map[string]string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+
+			key := fileKey(field, tagName)
+			if key == "-" {
+				continue
+			}
+
+			expected[key] = field.Type
+			folded[strings.ToLower(key)] = key
+
+			aliases, isDeprecated := getAliases(field.Tag)
+			for _, alias := range aliases {
+				aliasOf[alias] = key
+			}
+			deprecated[key] = isDeprecated
+		}
+
+		type source struct {
+			key   string
+			alias bool
+		}
+
+		// sort so that renaming and collision warnings are deterministic
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var targets []string
+		sources := map[string][]source{}
+		for _, k := range keys {
+			target, alias := k, false
+			if _, exact := expected[k]; !exact {
+				if canonical, ok := aliasOf[k]; ok {
+					target, alias = canonical, true
+				} else if cp.o.config.caseInsensitive {
+					if canonical, ok := folded[strings.ToLower(k)]; ok {
+						target = canonical
+					} else {
+						for a, canonical := range aliasOf {
+							if strings.EqualFold(a, k) {
+								target, alias = canonical, true
+								break
+							}
+						}
+					}
+				}
+			}
+
+			if _, ok := sources[target]; !ok {
+				targets = append(targets, target)
+			}
+			sources[target] = append(sources[target], source{key: k, alias: alias})
+		}
+
+		result := make(map[string]any, len(m))
+		for _, target := range targets {
+			candidates := sources[target]
+			keyPath := strings.Join(append(path, target), ".")
+
+			chosen := candidates[0]
+			if len(candidates) > 1 {
+				for _, candidate := range candidates {
+					if candidate.alias {
+						return nil, fmt.Errorf("%w: config keys %q and %q are both set for %q, only one may be used", errFatal, candidates[0].key, candidates[1].key, keyPath)
+					}
+				}
+
+				// prefer the key that matches exactly, otherwise the first seen
+				for _, candidate := range candidates {
+					if candidate.key == target {
+						chosen = candidate
+					}
+				}
+
+				var collided []string
+				for _, candidate := range candidates {
+					collided = append(collided, candidate.key)
+				}
+
+				cp.o.warn(fmt.Errorf("config keys %q collide after case folding at %q, using %q", collided, keyPath, chosen.key))
+			}
+
+			if chosen.alias && deprecated[target] {
+				cp.o.warn(fmt.Errorf("config key %q is deprecated, use %q instead", strings.Join(append(path, chosen.key), "."), keyPath))
+			}
+
+			v := m[chosen.key]
+			if fieldType, ok := expected[target]; ok {
+				var err error
+				v, err = cp.rewriteKeys(v, fieldType, tagName, append(path, target))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			result[target] = v
+		}
+
+		return result, nil
+	case reflect.Slice, reflect.Array:
+		s, ok := doc.([]any)
+		if !ok {
+			return doc, nil
+		}
+
+		for i := range s {
+			var err error
+			s[i], err = cp.rewriteKeys(s[i], t.Elem(), tagName, append(path, fmt.Sprintf("%d", i)))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return s, nil
+	default:
+		return doc, nil
+	}
+}
+
+// hasAliases checks whether any field within t has a confy_alias tag, and thus needs its keys rewritten
+func hasAliases(t reflect.Type) bool {
+	return typeHasAliases(t, map[reflect.Type]bool{})
+}
+
+// typeHasAliases does the work of hasAliases, visited stops self referential types (e.g a linked list) from recursing forever
+func typeHasAliases(t reflect.Type, visited map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup(confyAliasTag); ok {
+			return true
+		}
+
+		if typeHasAliases(field.Type, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fileKey returns the key a decoder will look for based on the tags added by createModifiedType
+func fileKey(field reflect.StructField, tagName string) string {
+	value, ok := field.Tag.Lookup(tagName)
+	if !ok {
+		return field.Name
+	}
+
+	name, _, _ := strings.Cut(value, ",")
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
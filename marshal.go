@@ -0,0 +1,152 @@
+package confy
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrConfigPrinted is returned by Config when the flag set by WithPrintConfigFlag was used, the configuration has been written to the flag set's output (stdout by default) and the program should exit
+var ErrConfigPrinted = errors.New("configuration was printed")
+
+// Marshal encodes cfg as json, yaml or toml using the same keys confy reads from config files (honouring confy:"name" renames and WithNaming)
+// Fields with the sensitive modifier are redacted in the same way as they are in logs, so the output may not be loadable as is
+func Marshal[T any](cfg T, configType ConfigType, suppliedOptions ...OptionFunc) ([]byte, error) {
+	if reflect.TypeOf(cfg).Kind() != reflect.Struct {
+		panic("Marshal(...) only supports configs of Struct type")
+	}
+
+	o := options{
+		currentlySet: make(map[preference]bool),
+	}
+
+	var errs []error
+	for _, optFunc := range suppliedOptions {
+		if err := optFunc(&o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return marshal(&o, cfg, configType)
+}
+
+// marshal does the work of Marshal with options that have already been applied, so that Config can print the config without running them again
+func marshal[T any](o *options, cfg T, configType ConfigType) ([]byte, error) {
+	original := reflect.ValueOf(cfg)
+
+	redacted := reflect.New(encodableType(newConfigLoader[T](o).createModifiedType(original.Type()), original.Type(), true)).Elem()
+	copyRedacted(redacted, original)
+
+	switch configType {
+	case Json:
+		return json.MarshalIndent(redacted.Interface(), "", "  ")
+	case Yaml:
+		return yaml.Marshal(redacted.Interface())
+	case Toml:
+		return toml.Marshal(redacted.Interface())
+	default:
+		return nil, fmt.Errorf("cannot marshal config as %q, must be one of %s, %s or %s", configType, Json, Yaml, Toml)
+	}
+}
+
+// encodableType restores the types within modified that encode themselves, and if redact is set replaces the type of sensitive fields with string so that they can hold the mask
+// original is the type modified was created from, and holds the confy tags
+func encodableType(modified, original reflect.Type, redact bool) reflect.Type {
+	if encodesItself(original) {
+		// e.g time.Time, the modified copy has lost the methods that encode it
+		return original
+	}
+
+	switch modified.Kind() {
+	case reflect.Struct:
+		fields := make([]reflect.StructField, modified.NumField())
+		for i := range fields {
+			fields[i] = modified.Field(i)
+
+			if redact && hasModifier(original.Field(i).Tag, confyTag, "sensitive") {
+				fields[i].Type = reflect.TypeOf("")
+				continue
+			}
+
+			fields[i].Type = encodableType(fields[i].Type, original.Field(i).Type, redact)
+		}
+
+		return reflect.StructOf(fields)
+	case reflect.Slice:
+		if original.Kind() != reflect.Slice {
+			return modified
+		}
+		return reflect.SliceOf(encodableType(modified.Elem(), original.Elem(), redact))
+	case reflect.Array:
+		if original.Kind() != reflect.Array {
+			return modified
+		}
+		return reflect.ArrayOf(modified.Len(), encodableType(modified.Elem(), original.Elem(), redact))
+	default:
+		return modified
+	}
+}
+
+// copyRedacted copies src in to dst, a value of the type created by encodableType, masking sensitive fields
+func copyRedacted(dst, src reflect.Value) {
+	if dst.Type() == src.Type() {
+		dst.Set(src)
+		return
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		for i := 0; i < dst.NumField(); i++ {
+			field := dst.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+
+			tag := src.Type().Field(i).Tag
+			if hasModifier(tag, confyTag, "sensitive") {
+				// as with maskSensitive, unset values are left empty
+				if !src.Field(i).IsZero() {
+					field.SetString(maskSensitive(fmt.Sprint(src.Field(i).Interface()), tag))
+				}
+				continue
+			}
+
+			copyRedacted(field, src.Field(i))
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+
+		dst.Set(reflect.MakeSlice(dst.Type(), src.Len(), src.Len()))
+		for i := 0; i < src.Len(); i++ {
+			copyRedacted(dst.Index(i), src.Index(i))
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			copyRedacted(dst.Index(i), src.Index(i))
+		}
+	default:
+		// the types only differ by their tags
+		dst.Set(src.Convert(dst.Type()))
+	}
+}
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func encodesItself(t reflect.Type) bool {
+	return t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) ||
+		t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType)
+}
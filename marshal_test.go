@@ -0,0 +1,131 @@
+package confy
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+type marshalStruct struct {
+	Name     string `confy:"name"`
+	Started  time.Time
+	Database struct {
+		Host     string
+		Password string `confy:"password;sensitive"`
+		Pin      int    `confy:"pin;sensitive"`
+		Unset    string `confy:"unset;sensitive"`
+	} `confy:"database"`
+	Backends []struct {
+		Address string `confy:"address"`
+		Token   string `confy:"token;sensitive"`
+	} `confy:"backends"`
+}
+
+func TestMarshal(t *testing.T) {
+
+	var config marshalStruct
+	config.Name = "app"
+	config.Started = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	config.Database.Host = "localhost"
+	config.Database.Password = "hunter2"
+	config.Database.Pin = 1234
+	config.Backends = append(config.Backends, struct {
+		Address string `confy:"address"`
+		Token   string `confy:"token;sensitive"`
+	}{Address: "10.0.0.1", Token: "secret"})
+
+	for _, configType := range []ConfigType{Json, Yaml, Toml} {
+		output, err := Marshal(config, configType)
+		if err != nil {
+			t.Fatal(configType, err)
+		}
+
+		s := string(output)
+		for _, secret := range []string{"hunter2", "1234", "secret"} {
+			if strings.Contains(s, secret) {
+				t.Fatalf("%s output contains sensitive value %q:\n%s", configType, secret, s)
+			}
+		}
+
+		for _, expected := range []string{"name", "app", "database", "localhost", "password", "**********", "backends", "10.0.0.1", "2024-01-02T03:04:05Z"} {
+			if !strings.Contains(s, expected) {
+				t.Fatalf("%s output missing %q:\n%s", configType, expected, s)
+			}
+		}
+	}
+
+	output, err := Marshal(config, Json)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(output), `"unset": ""`) {
+		t.Fatalf("unset sensitive values should not be masked:\n%s", output)
+	}
+
+	if _, err := Marshal(config, Auto); err == nil {
+		t.Fatal("expected Auto to be an error")
+	}
+}
+
+func TestPrintConfigFlag(t *testing.T) {
+
+	_, _, err := Config[marshalStruct](FromCli(CLIDelimiter), WithPrintConfigFlag("print-config"), WithArgs([]string{"-name", "app", "-print-config", "yaml"}))
+	if !errors.Is(err, ErrConfigPrinted) {
+		t.Fatalf("expected ErrConfigPrinted got: %v", err)
+	}
+
+	config, _, err := Config[marshalStruct](FromCli(CLIDelimiter), WithPrintConfigFlag("print-config"), WithArgs([]string{"-name", "app"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Name != "app" {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+
+	_, _, err = Config[marshalStruct](FromCli(CLIDelimiter), WithPrintConfigFlag("print-config"), WithArgs([]string{"-name", "app", "-print-config", "xml"}))
+	if err == nil || errors.Is(err, ErrConfigPrinted) {
+		t.Fatalf("expected unsupported format to be an error got: %v", err)
+	}
+}
+
+func TestPrintConfigFlagOutput(t *testing.T) {
+
+	var output bytes.Buffer
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&output)
+
+	options := []OptionFunc{FromCli(CLIDelimiter), WithPrintConfigFlag("print-config")}
+	if err := RegisterFlags[marshalStruct](fs, options...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Parse([]string{"-name", "app", "-print-config", "yaml"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := Config[marshalStruct](append(options, WithFlagSet(fs))...)
+	if !errors.Is(err, ErrConfigPrinted) {
+		t.Fatalf("expected ErrConfigPrinted got: %v", err)
+	}
+
+	if !strings.Contains(output.String(), "name: app") {
+		t.Fatalf("config was not written to the flag set's output: %q", output.String())
+	}
+
+	fs.SetOutput(failingWriter{})
+	_, _, err = Config[marshalStruct](append(options, WithFlagSet(fs))...)
+	if err == nil || errors.Is(err, ErrConfigPrinted) {
+		t.Fatalf("expected the failed write to be returned, got: %v", err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
@@ -0,0 +1,115 @@
+package confy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy controls how the words of a generated env variable, cli flag or config file key are cased and joined
+type NamingStrategy int
+
+const (
+	// AsIs uses the go field name (or confy tag name) unchanged, joined with the configured delimiter
+	AsIs NamingStrategy = iota
+	// SnakeCase results in database_max_conns
+	SnakeCase
+	// ScreamingSnake results in DATABASE_MAX_CONNS
+	ScreamingSnake
+	// KebabCase results in database-max-conns
+	KebabCase
+	// CamelCase results in databaseMaxConns
+	CamelCase
+)
+
+// Naming sets the naming strategy for each of the configuration sources
+type Naming struct {
+	Env  NamingStrategy
+	Cli  NamingStrategy
+	File NamingStrategy
+}
+
+// join converts the resolved path components in to a single name
+// AsIs keeps the components as they are and joins them with delimiter, every other strategy splits all components in to words and ignores the delimiter
+func (n NamingStrategy) join(components []string, delimiter string) string {
+	if n == AsIs {
+		return strings.Join(components, delimiter)
+	}
+
+	var words []string
+	for _, component := range components {
+		words = append(words, splitWords(component)...)
+	}
+
+	return n.format(words)
+}
+
+// convert changes a single name (e.g a config file key) to the naming strategy
+func (n NamingStrategy) convert(name string) string {
+	if n == AsIs {
+		return name
+	}
+
+	return n.format(splitWords(name))
+}
+
+func (n NamingStrategy) format(words []string) string {
+	switch n {
+	case SnakeCase:
+		return strings.ToLower(strings.Join(words, "_"))
+	case ScreamingSnake:
+		return strings.ToUpper(strings.Join(words, "_"))
+	case KebabCase:
+		return strings.ToLower(strings.Join(words, "-"))
+	case CamelCase:
+		var sb strings.Builder
+		for i, word := range words {
+			word = strings.ToLower(word)
+			if i > 0 && word != "" {
+				word = strings.ToUpper(word[:1]) + word[1:]
+			}
+			sb.WriteString(word)
+		}
+		return sb.String()
+	default:
+		return strings.Join(words, "")
+	}
+}
+
+// splitWords splits a name in to words on case changes and separators, keeping acronyms together
+// e.g HTTPPort -> [HTTP Port], MaxConns -> [Max Conns], max_conns -> [max conns]
+func splitWords(name string) []string {
+	var (
+		words   []string
+		current []rune
+	)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == '.' || unicode.IsSpace(r) {
+			flush()
+			continue
+		}
+
+		if unicode.IsUpper(r) && len(current) > 0 {
+			previous := current[len(current)-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			// lower -> Upper is a new word, as is the last capital of an acronym followed by lower case (HTTPPort)
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				flush()
+			}
+		}
+
+		current = append(current, r)
+	}
+	flush()
+
+	return words
+}
@@ -0,0 +1,83 @@
+package confy
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	cases := map[string][]string{
+		"MaxConns":  {"Max", "Conns"},
+		"HTTPPort":  {"HTTP", "Port"},
+		"max_conns": {"max", "conns"},
+		"ID":        {"ID"},
+		"userID":    {"user", "ID"},
+		"Port2Name": {"Port2", "Name"},
+	}
+
+	for input, expected := range cases {
+		actual := splitWords(input)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("splitting %q expected %v got %v", input, expected, actual)
+		}
+	}
+}
+
+func TestNamingStrategies(t *testing.T) {
+	type namingStruct struct {
+		Database struct {
+			MaxConns int
+			Host     string
+		}
+		HTTPPort int
+		Renamed  string `confy:"explicit_Name"`
+	}
+
+	os.Args = []string{"dummy", "-database-max-conns", "10"}
+	t.Setenv("DATABASE_HOST", "env_host")
+	t.Setenv("HTTP_PORT", "8080")
+
+	yamlConfig := []byte(`
+database:
+  max_conns: 5
+  host: file_host
+explicit_Name: kept
+`)
+
+	config, _, err := Config[namingStruct](
+		FromConfigBytes(yamlConfig, Yaml),
+		FromEnvs(ENVDelimiter),
+		FromCli(CLIDelimiter),
+		WithNaming(Naming{Env: ScreamingSnake, Cli: KebabCase, File: SnakeCase}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Database.MaxConns != 10 {
+		t.Errorf("expected cli to set max conns to 10 got %d", config.Database.MaxConns)
+	}
+
+	if config.Database.Host != "env_host" {
+		t.Errorf("expected env to set host got %q", config.Database.Host)
+	}
+
+	if config.HTTPPort != 8080 {
+		t.Errorf("expected env to set http port got %d", config.HTTPPort)
+	}
+
+	if config.Renamed != "kept" {
+		t.Errorf("expected explicit confy name to be used as file key, got %q", config.Renamed)
+	}
+
+	expectedEnvs := []string{"DATABASE_MAX_CONNS", "DATABASE_HOST", "HTTP_PORT", "EXPLICIT_NAME"}
+	envs, err := GetGeneratedEnvWithOptions[namingStruct](WithNaming(Naming{Env: ScreamingSnake}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expectedEnvs, envs) {
+		t.Fatalf("expected %v got %v", expectedEnvs, envs)
+	}
+}
@@ -3,6 +3,7 @@ package confy
 import (
 	"encoding"
 	"reflect"
+	"slices"
 	"strings"
 )
 
@@ -105,29 +106,73 @@ func getField(v interface{}, fieldPath []string) (reflect.Value, reflect.StructF
 	return reflect.Value{}, reflect.StructField{}
 }
 
+// getConfyField returns the field within the struct v at the confy path (field names as resolveName gives them), following pointers
+// the returned value is invalid if there is no such field or a pointer along the way is nil
+func getConfyField(v reflect.Value, path []string) reflect.Value {
+	for _, name := range path {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+
+		field := reflect.Value{}
+		for i := 0; i < v.NumField(); i++ {
+			if sf := v.Type().Field(i); sf.IsExported() && resolveName(sf) == name {
+				field = v.Field(i)
+				break
+			}
+		}
+
+		if !field.IsValid() {
+			return field
+		}
+		v = field
+	}
+
+	return v
+}
+
 func resolvePath(v interface{}, fieldPath []string) []string {
 	resolvedPath := []string{}
 	for i := range fieldPath {
 
-		currentPath := fieldPath[i]
 		_, ft := getField(v, fieldPath[:i+1])
+		currentPath := resolveName(ft)
 
-		value, ok := ft.Tag.Lookup(confyTag)
-		if ok {
-
-			parts := strings.Split(value, ";")
-			if len(parts) > 0 && parts[0] != "" {
-				currentPath = parts[0]
-			}
-		}
-
-		logger.Info("resolving path", "tags", ft.Tag, "had_confy_tag", ok, "current_path", fieldPath[:i+1])
+		logger.Info("resolving path", "tags", ft.Tag, "current_path", fieldPath[:i+1])
 
 		resolvedPath = append(resolvedPath, currentPath)
 	}
 	return resolvedPath
 }
 
+// resolveName returns the name confy uses for a struct field, the name from its confy tag, its command name or otherwise the field name
+func resolveName(field reflect.StructField) string {
+	name := field.Name
+
+	value, ok := field.Tag.Lookup(confyTag)
+	if ok {
+
+		parts := strings.Split(value, ";")
+		if len(parts) > 0 && parts[0] != "" {
+			name = parts[0]
+		}
+	}
+
+	// commands are named by their command name, unless explicitly renamed
+	if cmd, isCmd := field.Tag.Lookup(confyCmdTag); isCmd && cmd != "" && name == field.Name {
+		name = cmd
+	}
+
+	return name
+}
+
 func equalStringSlices(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -140,34 +185,61 @@ func equalStringSlices(a, b []string) bool {
 	return true
 }
 
-func maskSensitive(value string, tag reflect.StructTag) string {
+// hasModifier checks whether the ; separated modifiers (everything after the name) of tagName contain modifier
+func hasModifier(tag reflect.StructTag, tagName, modifier string) bool {
+	value, ok := tag.Lookup(tagName)
+	if !ok {
+		return false
+	}
 
-	printedValue := value
+	parts := strings.Split(value, ";")
+	for _, part := range parts[1:] {
+		if strings.TrimSpace(part) == modifier {
+			return true
+		}
+	}
 
-	isSensitive := false
-	value, ok := tag.Lookup(confyTag)
-	if ok {
-		parts := strings.Split(value, ";")
-		if len(parts) > 1 {
-			isSensitive = strings.TrimSpace(parts[1]) == "sensitive"
+	return false
+}
+
+// getAliases returns the alternative names from confy_alias:"old_name,older_name;deprecated" and whether using them is deprecated
+func getAliases(tag reflect.StructTag) (aliases []string, deprecated bool) {
+	value, ok := tag.Lookup(confyAliasTag)
+	if !ok {
+		return nil, false
+	}
+
+	names, _, _ := strings.Cut(value, ";")
+	for _, alias := range strings.Split(names, ",") {
+		alias = strings.TrimSpace(alias)
+		if alias != "" {
+			aliases = append(aliases, alias)
 		}
 	}
 
-	if isSensitive && value != "" {
-		printedValue = "**********"
+	return aliases, hasModifier(tag, confyAliasTag, "deprecated")
+}
+
+func maskSensitive(value string, tag reflect.StructTag) string {
+
+	if hasModifier(tag, confyTag, "sensitive") && value != "" {
+		return "**********"
 	}
 
-	return printedValue
+	return value
 }
 
-// determineVariableName returns the variable name after resolving and transforming
+// determineVariableName returns the variable name after resolving, prefixing and transforming
 // ok: bool indicates whether this is a decodable type
-func determineVariableName[T any](result *T, delimiter string, transform Transform, field fieldsData) (string, bool) {
-	variable := strings.Join(resolvePath(result, field.path), delimiter)
-	if transform != nil {
-		variable = transform(variable)
-		logger.Info("using transform func on variable", "before_func", strings.Join(resolvePath(result, field.path), delimiter), "after_func", variable)
-	}
+func determineVariableName[T any](result *T, naming envOptions, field fieldsData) (string, bool) {
+	return determineRelativeVariableName(result, naming, field, 0)
+}
+
+// determineRelativeVariableName is determineVariableName, but drops the first depth components of the path. Used for naming the flags of commands
+func determineRelativeVariableName[T any](result *T, naming envOptions, field fieldsData, depth int) (string, bool) {
+	delimiter := naming.delimiter
+
+	variable := buildVariableName(resolvePath(result, field.path)[depth:], naming)
 
 	if field.value.Kind() == reflect.Struct {
 		current := field.value
@@ -194,3 +266,36 @@ func determineVariableName[T any](result *T, delimiter string, transform Transfo
 
 	return variable, true
 }
+
+// determineAliasNames returns the variable names generated by replacing the fields own name with each of its confy_alias names
+// the first depth components of the path are dropped, as with determineRelativeVariableName
+func determineAliasNames[T any](result *T, naming envOptions, field fieldsData, depth int) (names []string, deprecated bool) {
+	aliases, deprecated := getAliases(field.tag)
+	if len(aliases) == 0 {
+		return nil, false
+	}
+
+	components := resolvePath(result, field.path)[depth:]
+	for _, alias := range aliases {
+		aliasComponents := append(slices.Clone(components[:len(components)-1]), alias)
+		names = append(names, buildVariableName(aliasComponents, naming))
+	}
+
+	return names, deprecated
+}
+
+func buildVariableName(components []string, naming envOptions) string {
+	if naming.prefix != "" {
+		components = append([]string{naming.prefix}, components...)
+	}
+
+	variable := naming.naming.join(components, naming.delimiter)
+
+	if naming.transform != nil {
+		before := variable
+		variable = naming.transform(variable)
+		logger.Info("using transform func on variable", "before_func", before, "after_func", variable)
+	}
+
+	return variable
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package confy
+
+import (
+	"os"
+)
+
+// reloadSignals returns the signals that trigger a reload in Watch, there is no SIGHUP to reload on outside of unix systems
+func (w watchOptions) reloadSignals() []os.Signal {
+	if w.onSIGHUP {
+		logger.Warn("reloading on SIGHUP is not supported on this platform, ignoring WithReloadOnSIGHUP")
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package confy
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals returns the signals that trigger a reload in Watch
+func (w watchOptions) reloadSignals() []os.Signal {
+	if !w.onSIGHUP {
+		return nil
+	}
+
+	return []os.Signal{syscall.SIGHUP}
+}
@@ -0,0 +1,124 @@
+//go:build unix
+
+package confy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReloadOnSIGHUP(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"Log": {"Level": "info"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloadErrors := make(chan error, 1)
+	// polling so rarely that only the signal can cause a reload
+	s, err := WatchStore[storeStruct](ctx,
+		FromConfigFile(path, Auto),
+		WithPollInterval(time.Hour),
+		WithReloadOnSIGHUP(),
+		WithReloadErrorHandler(func(err error) {
+			reloadErrors <- err
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan []string, 1)
+	s.Subscribe(func(old, new storeStruct, changed []string) {
+		changes <- changed
+	})
+
+	if err := os.WriteFile(path, []byte(`{"Log": {"Level": "debug"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-reloadErrors:
+		if !strings.Contains(err.Error(), "failed to reload config") {
+			t.Fatalf("unexpected reload error: %s", err)
+		}
+	case <-changes:
+		t.Fatal("invalid config was swapped into the store")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload error")
+	}
+
+	if s.Load().Log.Level != "info" {
+		t.Fatalf("expected last good config to be kept, got: %+v", s.Load())
+	}
+
+	if err := os.WriteFile(path, []byte(`{"Log": {"Level": "debug"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+
+	if s.Load().Log.Level != "debug" {
+		t.Fatalf("store was not updated after SIGHUP: %+v", s.Load())
+	}
+}
+
+func TestReloadOnSIGHUPWithoutFile(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var level atomic.Value
+	level.Store("info")
+	lookup := func(name string) (string, bool) {
+		if name == "Level" {
+			return level.Load().(string), true
+		}
+		return "", false
+	}
+
+	// the default config file does not exist, which Config accepts, so Watch must as well
+	initial, updates, err := Watch[watchStruct](ctx,
+		Defaults("config", filepath.Join(t.TempDir(), "config.json")),
+		WithArgs([]string{}),
+		WithLookupEnv(lookup),
+		WithReloadOnSIGHUP(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if initial.Level != "info" {
+		t.Fatalf("unexpected initial config: %+v", initial)
+	}
+
+	level.Store("debug")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	if update := receiveUpdate(t, updates); update.Level != "debug" {
+		t.Fatalf("unexpected update: %+v", update)
+	}
+}
@@ -0,0 +1,705 @@
+package confy
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultURLAttempts = 3
+	defaultURLBackoff  = 500 * time.Millisecond
+	maxURLBackoff      = 10 * time.Second
+	urlTimeout         = 20 * time.Second
+
+	// defaultURLPollInterval is how often Watch requests a FromConfigURL config without WithPollInterval
+	defaultURLPollInterval = 30 * time.Second
+	// minURLCheckInterval stops Watch busy looping against a server that answers long polls straight away
+	minURLCheckInterval = time.Second
+)
+
+type remoteOptions struct {
+	// attempts is the total number of requests made before giving up, 0 uses defaultURLAttempts
+	attempts int
+	backoff  time.Duration
+
+	cachePath string
+
+	client *http.Client
+
+	headers []urlHeader
+	bearer  *Secret
+
+	basicUser     string
+	basicPassword *Secret
+
+	caFile            string
+	certFile, keyFile string
+
+	// longPollParam is the query parameter that tells the server how long it may hold a request made by Watch
+	longPollParam string
+	longPollWait  time.Duration
+}
+
+type urlHeader struct {
+	name  string
+	value Secret
+}
+
+// Secret is a credential used by FromConfigURL, it is read each time the config is requested so that rotated credentials are picked up on reload
+type Secret struct {
+	value string
+	env   string
+	file  string
+}
+
+// SecretValue is a secret given directly
+func SecretValue(value string) Secret {
+	return Secret{value: value}
+}
+
+// SecretFromEnv reads a secret from the environment variable name, honouring WithEnviron and WithLookupEnv
+func SecretFromEnv(name string) Secret {
+	return Secret{env: name}
+}
+
+// SecretFromFile reads a secret from the file at path (e.g a mounted kubernetes or docker secret), surrounding whitespace is removed
+func SecretFromFile(path string) Secret {
+	return Secret{file: path}
+}
+
+func (s Secret) resolve(o *options) (string, error) {
+	switch {
+	case s.env != "":
+		value, ok := o.getEnv(s.env)
+		if !ok {
+			return "", fmt.Errorf("secret environment variable %q is not set", s.env)
+		}
+		return value, nil
+	case s.file != "":
+		content, err := os.ReadFile(s.file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	default:
+		return s.value, nil
+	}
+}
+
+// WithHTTPClient sets the client FromConfigURL requests the config with, instead of one with a 20 second timeout
+// It cannot be combined with WithURLCA or WithURLClientCert, configure the client's transport instead
+func WithHTTPClient(client *http.Client) OptionFunc {
+	return func(o *options) error {
+		if client == nil {
+			return errors.New("http client must not be nil")
+		}
+
+		o.config.remote.client = client
+		return nil
+	}
+}
+
+// WithURLHeader adds a header to the FromConfigURL request, e.g WithURLHeader("X-Api-Key", SecretFromEnv("CONFIG_API_KEY"))
+func WithURLHeader(name string, value Secret) OptionFunc {
+	return func(o *options) error {
+		if name == "" {
+			return errors.New("url header name must not be empty")
+		}
+
+		o.config.remote.headers = append(o.config.remote.headers, urlHeader{name: name, value: value})
+		return nil
+	}
+}
+
+// WithURLBearerToken authenticates the FromConfigURL request with an Authorization: Bearer header
+func WithURLBearerToken(token Secret) OptionFunc {
+	return func(o *options) error {
+		if o.config.remote.basicPassword != nil {
+			return errors.New("WithURLBearerToken and WithURLBasicAuth are mutually exclusive")
+		}
+
+		o.config.remote.bearer = &token
+		return nil
+	}
+}
+
+// WithURLBasicAuth authenticates the FromConfigURL request with http basic auth
+func WithURLBasicAuth(username string, password Secret) OptionFunc {
+	return func(o *options) error {
+		if o.config.remote.bearer != nil {
+			return errors.New("WithURLBearerToken and WithURLBasicAuth are mutually exclusive")
+		}
+
+		o.config.remote.basicUser = username
+		o.config.remote.basicPassword = &password
+		return nil
+	}
+}
+
+// WithURLCA trusts the PEM encoded certificates in the file at path when verifying the FromConfigURL server, in addition to the system roots
+func WithURLCA(path string) OptionFunc {
+	return func(o *options) error {
+		if path == "" {
+			return errors.New("url ca path must not be empty")
+		}
+
+		o.config.remote.caFile = path
+		return nil
+	}
+}
+
+// WithURLClientCert presents the PEM encoded certificate and key to the FromConfigURL server, for mutual TLS
+func WithURLClientCert(certFile, keyFile string) OptionFunc {
+	return func(o *options) error {
+		if certFile == "" || keyFile == "" {
+			return errors.New("url client certificate and key paths must not be empty")
+		}
+
+		o.config.remote.certFile = certFile
+		o.config.remote.keyFile = keyFile
+		return nil
+	}
+}
+
+// httpClient returns the client given with WithHTTPClient, or builds one from the TLS options
+// The files are read on each load and each watch request so that renewed certificates are picked up without a restart
+func (r remoteOptions) httpClient() (*http.Client, error) {
+	customTLS := r.caFile != "" || r.certFile != ""
+	if r.client != nil {
+		if customTLS {
+			return nil, errors.New("WithHTTPClient cannot be combined with WithURLCA or WithURLClientCert, configure the client's transport instead")
+		}
+
+		return r.client, nil
+	}
+
+	client := &http.Client{
+		Timeout: urlTimeout,
+	}
+
+	if !customTLS {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read url ca: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in url ca %q", r.caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if r.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load url client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	// programs may replace http.DefaultTransport with a wrapper (e.g for tracing), in which case its settings can't be copied
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if ok {
+		transport = transport.Clone()
+	} else {
+		transport = &http.Transport{Proxy: http.ProxyFromEnvironment}
+	}
+	transport.TLSClientConfig = tlsConfig
+	client.Transport = transport
+
+	return client, nil
+}
+
+// closeIdle closes the idle connections of a client httpClient built with its own transport
+// the WithHTTPClient client and http.DefaultTransport (used when there are no TLS options) are shared with the rest of the program, so are left alone
+func (r remoteOptions) closeIdle(client *http.Client) {
+	if r.client == nil && client.Transport != nil {
+		client.CloseIdleConnections()
+	}
+}
+
+// authorize adds the configured headers and credentials to req
+func (r remoteOptions) authorize(req *http.Request, o *options) error {
+	for _, header := range r.headers {
+		value, err := header.value.resolve(o)
+		if err != nil {
+			return fmt.Errorf("url header %s: %w", header.name, err)
+		}
+		req.Header.Set(header.name, value)
+	}
+
+	if r.bearer != nil {
+		token, err := r.bearer.resolve(o)
+		if err != nil {
+			return fmt.Errorf("url bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if r.basicPassword != nil {
+		password, err := r.basicPassword.resolve(o)
+		if err != nil {
+			return fmt.Errorf("url basic auth password: %w", err)
+		}
+		req.SetBasicAuth(r.basicUser, password)
+	}
+
+	return nil
+}
+
+// WithURLLongPoll makes Watch use blocking queries to wait for the FromConfigURL config to change, instead of polling it
+// Each request adds param=wait (e.g ?wait=5m0s) along with If-None-Match/If-Modified-Since from the last response, the server should hold the request until the config changes or wait elapses
+func WithURLLongPoll(param string, wait time.Duration) OptionFunc {
+	return func(o *options) error {
+		if param == "" {
+			return errors.New("url long poll parameter must not be empty")
+		}
+
+		if wait <= 0 {
+			return errors.New("url long poll wait must be positive")
+		}
+
+		o.config.remote.longPollParam = param
+		o.config.remote.longPollWait = wait
+		return nil
+	}
+}
+
+// WithURLRetries sets how many times FromConfigURL requests the config before giving up (including the first request), and how long it waits before the first retry
+// The wait doubles after each attempt, only network errors, 429 and 5xx responses are retried. Defaults to 3 attempts starting at 500ms
+func WithURLRetries(attempts int, backoff time.Duration) OptionFunc {
+	return func(o *options) error {
+		if attempts < 1 {
+			return errors.New("url attempts must be at least 1")
+		}
+
+		if backoff < 0 {
+			return errors.New("url retry backoff must not be negative")
+		}
+
+		o.config.remote.attempts = attempts
+		o.config.remote.backoff = backoff
+		return nil
+	}
+}
+
+// WithURLCache keeps the last FromConfigURL response that decoded and passed validation in the file at path
+// The cached ETag and Last-Modified are sent with the next request, and if the server cannot be reached the cached config is used with a warning that it may be stale
+// The file is written with 0600 permissions as configs often contain secrets
+func WithURLCache(path string) OptionFunc {
+	return func(o *options) error {
+		if path == "" {
+			return errors.New("url cache path must not be empty")
+		}
+
+		o.config.remote.cachePath = path
+		return nil
+	}
+}
+
+// urlCacheEntry is a response that loaded successfully, kept in memory between loads (e.g reloads by Watch) and on disk with WithURLCache
+type urlCacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+	Body         string    `json:"body"`
+}
+
+// remoteConfig is the state for a single FromConfigURL source, it lives as long as the option so that conditional requests can be made on reload
+type remoteConfig struct {
+	mu sync.Mutex
+
+	url        string
+	configType ConfigType
+
+	cached *urlCacheEntry
+}
+
+// errRetryable marks request failures that may succeed if tried again
+var errRetryable = errors.New("retryable")
+
+func (r *remoteConfig) load(o *options) (io.Reader, ConfigType, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return nil, r.configType, err
+	}
+
+	remote := o.config.remote
+	if r.cached == nil && remote.cachePath != "" {
+		r.cached = readURLCache(remote.cachePath, r.url)
+	}
+
+	entry, err := r.fetch(o)
+	switch {
+	case err == nil && entry == nil:
+		logger.Info("config at url was not modified, using cached copy", "url", r.url)
+		entry = r.cached
+	case err == nil:
+		// the response is only kept once it has decoded and passed validation, so that a bad response never replaces the last good config
+		o.config.loaded = func() error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			return r.store(remote, entry)
+		}
+	case errors.Is(err, errRetryable) && r.cached != nil:
+		o.warn(fmt.Errorf("config server unreachable, using stale config fetched from %s at %s: %w", r.url, r.cached.Fetched.Format(time.RFC3339), err))
+		entry = r.cached
+	default:
+		return nil, r.configType, err
+	}
+
+	fileType, err := urlConfigType(u, entry.ContentType, r.configType)
+	if err != nil {
+		return nil, r.configType, err
+	}
+
+	return strings.NewReader(entry.Body), fileType, nil
+}
+
+// store keeps entry as the latest response, writing it to the WithURLCache file if there is one. r.mu must be held
+func (r *remoteConfig) store(remote remoteOptions, entry *urlCacheEntry) error {
+	r.cached = entry
+	if remote.cachePath == "" {
+		return nil
+	}
+
+	if err := writeURLCache(remote.cachePath, entry); err != nil {
+		return fmt.Errorf("failed to write url cache %q: %w", remote.cachePath, err)
+	}
+
+	return nil
+}
+
+// watch signals on the returned channel when the config served at the url changes, the reload it triggers requests the config again and only keeps it if it is valid
+// Without WithURLLongPoll the url is requested every interval. The channel is closed once ctx is done
+func (r *remoteConfig) watch(ctx context.Context, o *options, interval time.Duration) (<-chan struct{}, error) {
+	remote := o.config.remote
+
+	// a client is built for every request, as on load, so that renewed certificates are picked up while watching
+	newClient := func() (*http.Client, error) {
+		client, err := remote.httpClient()
+		if err != nil {
+			return nil, err
+		}
+
+		if remote.longPollParam != "" && client.Timeout != 0 {
+			// the server may hold the request for the whole wait
+			longPoll := *client
+			longPoll.Timeout = max(longPoll.Timeout, remote.longPollWait+urlTimeout)
+			client = &longPoll
+		}
+
+		return client, nil
+	}
+
+	// built up front so that bad TLS options are returned rather than retried
+	if _, err := newClient(); err != nil {
+		return nil, err
+	}
+
+	rawURL := r.url
+	if remote.longPollParam != "" {
+		u, err := url.Parse(r.url)
+		if err != nil {
+			return nil, err
+		}
+
+		query := u.Query()
+		query.Set(remote.longPollParam, remote.longPollWait.String())
+		u.RawQuery = query.Encode()
+		rawURL = u.String()
+	}
+
+	// seen is the last response the watcher got, kept apart from r.cached so that a response that fails to load is not requested over and over
+	r.mu.Lock()
+	seen := r.cached
+	r.mu.Unlock()
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+
+		backoff := defaultURLBackoff
+		for {
+			wait := interval
+			if remote.longPollParam != "" {
+				wait = 0
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+
+			started := time.Now()
+
+			var entry *urlCacheEntry
+			client, err := newClient()
+			if err == nil {
+				entry, err = r.request(ctx, o, client, rawURL, seen)
+				remote.closeIdle(client)
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				logger.Warn("failed to check url for config changes", "url", r.url, "backoff", backoff, "err", err.Error())
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, maxURLBackoff)
+				continue
+			}
+			backoff = defaultURLBackoff
+
+			if entry != nil {
+				previous := seen
+				seen = entry
+
+				if previous == nil || entry.Body != previous.Body {
+					logger.Info("config at url changed", "url", r.url)
+					select {
+					case changes <- struct{}{}:
+					default:
+					}
+					continue
+				}
+			}
+
+			if remote.longPollParam != "" {
+				// the server answered without waiting, so it may not support blocking queries
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(minURLCheckInterval - time.Since(started)):
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// fetch requests the config, retrying with exponential backoff. A nil entry and error means the server reported the cached copy is current
+func (r *remoteConfig) fetch(o *options) (*urlCacheEntry, error) {
+	remote := o.config.remote
+
+	client, err := remote.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	defer remote.closeIdle(client)
+
+	attempts := remote.attempts
+	backoff := remote.backoff
+	if attempts == 0 {
+		attempts = defaultURLAttempts
+		backoff = defaultURLBackoff
+	}
+
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var entry *urlCacheEntry
+		entry, err = r.request(ctx, o, client, r.url, r.cached)
+		if err == nil || !errors.Is(err, errRetryable) {
+			return entry, err
+		}
+
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to get config from url: %s: %w", r.url, ctx.Err())
+		}
+
+		if attempt < attempts {
+			logger.Warn("failed to get config from url, retrying", "url", r.url, "attempt", attempt, "backoff", backoff, "err", err.Error())
+
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("failed to get config from url: %s: %w", r.url, ctx.Err())
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxURLBackoff)
+		}
+	}
+
+	return nil, fmt.Errorf("failed to get config from url: %s after %d attempts, err: %w", r.url, attempts, err)
+}
+
+// request gets the config from rawURL once, making it conditional on cached if it is set. A nil entry and error means the server reported cached is current
+func (r *remoteConfig) request(ctx context.Context, o *options, client *http.Client, rawURL string, cached *urlCacheEntry) (*urlCacheEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.config.remote.authorize(req, o); err != nil {
+		return nil, err
+	}
+
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errRetryable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		return nil, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, fmt.Errorf("%w: status code was not okay: %s", errRetryable, resp.Status)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("status code was not okay: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read config from url: %w", errRetryable, err)
+	}
+
+	return &urlCacheEntry{
+		URL:          r.url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentType:  resp.Header.Get("Content-Type"),
+		Fetched:      time.Now(),
+		Body:         string(body),
+	}, nil
+}
+
+// urlConfigType determines the config type from the url path extension, or the content type if there is no extension
+func urlConfigType(u *url.URL, contentType string, configType ConfigType) (ConfigType, error) {
+	if configType != Auto {
+		return configType, nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(u.Path))
+	switch ext {
+	case ".yml", ".yaml":
+		logger.Info("yaml chosen as config type from extension", "url_path", u.Path)
+
+		return Yaml, nil
+	case ".json", ".js":
+		logger.Info("json chosen as config type from extension", "url_path", u.Path)
+
+		return Json, nil
+	case ".toml", ".tml":
+		logger.Info("toml chosen as config type from extension", "url_path", u.Path)
+
+		return Toml, nil
+	}
+
+	logger.Info("no extension in url, using content type instead")
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(mediaType) {
+	case "application/yaml", "application/x-yaml", "text/yaml":
+		return Yaml, nil
+	case "application/json":
+		return Json, nil
+	case "text/x-toml", "application/toml", "text/toml":
+		return Toml, nil
+	default:
+		return configType, fmt.Errorf("could not automatically determine config format from extension %q or content-type %q", ext, contentType)
+	}
+}
+
+// readURLCache returns the cached response for rawURL, or nil if there is no usable cache
+func readURLCache(path, rawURL string) *urlCacheEntry {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("failed to read url cache", "path", path, "err", err.Error())
+		}
+		return nil
+	}
+
+	var entry urlCacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		logger.Warn("url cache is corrupt, ignoring it", "path", path, "err", err.Error())
+		return nil
+	}
+
+	if entry.URL != rawURL {
+		logger.Info("url cache is for a different url, ignoring it", "path", path, "cached_url", entry.URL)
+		return nil
+	}
+
+	return &entry
+}
+
+// writeURLCache replaces the cache file at path with entry, via a rename so that a crash cannot leave a partially written cache
+func writeURLCache(path string, entry *urlCacheEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := io.Copy(temp, bytes.NewReader(content)); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
@@ -0,0 +1,642 @@
+package confy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type remoteStruct struct {
+	Level string
+}
+
+func TestURLConditionalRequests(t *testing.T) {
+
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"Level": "info"}`))
+	}))
+	defer server.Close()
+
+	opts := []OptionFunc{FromConfigURL(server.URL+"/config", Auto)}
+	for i := 0; i < 2; i++ {
+		result, _, err := Config[remoteStruct](opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Level != "info" {
+			t.Fatalf("unexpected config on load %d: %+v", i, result)
+		}
+	}
+
+	if requests.Load() != 2 || notModified.Load() != 1 {
+		t.Fatalf("expected the second load to be a conditional request, requests: %d, not modified: %d", requests.Load(), notModified.Load())
+	}
+}
+
+func TestURLRetries(t *testing.T) {
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte(`{"Level": "debug"}`))
+	}))
+	defer server.Close()
+
+	result, _, err := Config[remoteStruct](FromConfigURL(server.URL+"/config.json", Auto), WithURLRetries(3, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Level != "debug" || requests.Load() != 3 {
+		t.Fatalf("expected success on the third attempt, got %+v after %d requests", result, requests.Load())
+	}
+
+	requests.Store(0)
+	_, _, err = Config[remoteStruct](FromConfigURL(server.URL+"/config.json", Auto), WithURLRetries(2, time.Millisecond))
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Fatalf("expected failure after 2 attempts, got: %v", err)
+	}
+
+	// client errors are not retried
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	_, _, err = Config[remoteStruct](FromConfigURL(missing.URL+"/config.json", Auto), WithURLRetries(3, time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected 404 error, got: %v", err)
+	}
+}
+
+func TestURLRetriesStopOnCancel(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, _, _, err := load[remoteStruct](ctx, []OptionFunc{FromConfigURL(server.URL+"/config.json", Auto), WithURLRetries(5, time.Hour)})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the retries to stop with the context, got: %v", err)
+	}
+
+	if time.Since(started) > 5*time.Second {
+		t.Fatalf("retries continued after the context was done, took %s", time.Since(started))
+	}
+}
+
+func TestURLCacheFallback(t *testing.T) {
+
+	cache := filepath.Join(t.TempDir(), "config.cache")
+
+	var conditional atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") != "" {
+			conditional.Store(true)
+		}
+
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte(`{"Level": "warn"}`))
+	}))
+
+	if _, _, err := Config[remoteStruct](FromConfigURL(server.URL+"/config.json", Auto), WithURLCache(cache)); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected cache to be written with 0600 permissions, got %v", info.Mode().Perm())
+	}
+
+	// a new source sends the Last-Modified kept in the disk cache
+	if _, _, err := Config[remoteStruct](FromConfigURL(server.URL+"/config.json", Auto), WithURLCache(cache)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !conditional.Load() {
+		t.Fatal("expected the cached Last-Modified to be sent")
+	}
+
+	server.Close()
+
+	result, warnings, err := Config[remoteStruct](FromConfigURL(server.URL+"/config.json", Auto), WithURLCache(cache), WithURLRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Level != "warn" {
+		t.Fatalf("expected cached config to be used, got: %+v", result)
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "stale config") {
+		t.Fatalf("expected a stale config warning, got: %v", warnings)
+	}
+}
+
+type validatedStruct struct {
+	Level string
+}
+
+func (v validatedStruct) Validate() error {
+	if v.Level == "trace" {
+		return errors.New("trace is not allowed")
+	}
+	return nil
+}
+
+func TestURLCacheKeepsLastGoodConfig(t *testing.T) {
+
+	cache := filepath.Join(t.TempDir(), "config.cache")
+
+	var body atomic.Value
+	body.Store(`{"Level": "info"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body.Load().(string)))
+	}))
+
+	opts := []OptionFunc{FromConfigURL(server.URL+"/config.json", Auto), WithURLCache(cache), WithURLRetries(1, 0)}
+	if _, _, err := Config[validatedStruct](opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []string{`{"Level": `, `{"Level": "trace"}`} {
+		body.Store(bad)
+		if _, _, err := Config[validatedStruct](opts...); err == nil {
+			t.Fatalf("expected %q to fail to load", bad)
+		}
+	}
+
+	server.Close()
+
+	// both the in memory copy and the disk cache still hold the last config that loaded
+	for _, source := range [][]OptionFunc{opts, {FromConfigURL(server.URL+"/config.json", Auto), WithURLCache(cache), WithURLRetries(1, 0)}} {
+		result, warnings, err := Config[validatedStruct](source...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Level != "info" {
+			t.Fatalf("expected the last good config to be used, got: %+v", result)
+		}
+
+		if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "stale config") {
+			t.Fatalf("expected a stale config warning, got: %v", warnings)
+		}
+	}
+}
+
+func TestURLAuthentication(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, basic := r.BasicAuth()
+		switch {
+		case r.Header.Get("X-Api-Key") != "key-from-file":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/bearer.json" && r.Header.Get("Authorization") == "Bearer token-from-env":
+			w.Write([]byte(`{"Level": "bearer"}`))
+		case r.URL.Path == "/basic.json" && basic && user == "admin" && password == "hunter2":
+			w.Write([]byte(`{"Level": "basic"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	keyFile := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(keyFile, []byte("key-from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, _, err := Config[remoteStruct](
+		FromConfigURL(server.URL+"/bearer.json", Auto),
+		WithURLHeader("X-Api-Key", SecretFromFile(keyFile)),
+		WithURLBearerToken(SecretFromEnv("CONFIG_TOKEN")),
+		WithEnviron(map[string]string{"CONFIG_TOKEN": "token-from-env"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Level != "bearer" {
+		t.Fatalf("unexpected config: %+v", result)
+	}
+
+	result, _, err = Config[remoteStruct](
+		FromConfigURL(server.URL+"/basic.json", Auto),
+		WithURLHeader("X-Api-Key", SecretFromFile(keyFile)),
+		WithURLBasicAuth("admin", SecretValue("hunter2")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Level != "basic" {
+		t.Fatalf("unexpected config: %+v", result)
+	}
+
+	_, _, err = Config[remoteStruct](
+		FromConfigURL(server.URL+"/bearer.json", Auto),
+		WithURLBearerToken(SecretFromEnv("CONFIG_TOKEN")),
+		WithEnviron(map[string]string{}),
+	)
+	if err == nil || !strings.Contains(err.Error(), `"CONFIG_TOKEN" is not set`) {
+		t.Fatalf("expected missing secret error, got: %v", err)
+	}
+
+	_, _, err = Config[remoteStruct](
+		FromConfigURL(server.URL+"/bearer.json", Auto),
+		WithURLBearerToken(SecretValue("a")),
+		WithURLBasicAuth("admin", SecretValue("b")),
+	)
+	if err == nil {
+		t.Fatal("expected bearer and basic auth to be mutually exclusive")
+	}
+}
+
+// writeTestCertificate writes a self signed certificate and its key as PEM files, returning the certificate
+func writeTestCertificate(t *testing.T, dir, name string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile, cert
+}
+
+func TestURLTLS(t *testing.T) {
+
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeTestCertificate(t, dir, "client")
+
+	clients := x509.NewCertPool()
+	clients.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Level": "mtls"}`))
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clients,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	ca := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, _, err := Config[remoteStruct](
+		FromConfigURL(server.URL+"/config.json", Auto),
+		WithURLCA(ca),
+		WithURLClientCert(certFile, keyFile),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Level != "mtls" {
+		t.Fatalf("unexpected config: %+v", result)
+	}
+
+	// without the client certificate the server rejects the handshake
+	_, _, err = Config[remoteStruct](
+		FromConfigURL(server.URL+"/config.json", Auto),
+		WithURLCA(ca),
+		WithURLRetries(1, 0),
+	)
+	if err == nil {
+		t.Fatal("expected request without a client certificate to fail")
+	}
+
+	// a supplied client is used as is
+	client := server.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{mustLoadKeyPair(t, certFile, keyFile)}
+
+	result, _, err = Config[remoteStruct](FromConfigURL(server.URL+"/config.json", Auto), WithHTTPClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Level != "mtls" {
+		t.Fatalf("unexpected config: %+v", result)
+	}
+
+	_, _, err = Config[remoteStruct](FromConfigURL(server.URL+"/config.json", Auto), WithHTTPClient(client), WithURLCA(ca))
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("expected WithHTTPClient and WithURLCA to conflict, got: %v", err)
+	}
+
+	// programs that wrap http.DefaultTransport (e.g for tracing) can still use the tls options
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = roundTripperFunc(defaultTransport.RoundTrip)
+	defer func() {
+		http.DefaultTransport = defaultTransport
+	}()
+
+	result, _, err = Config[remoteStruct](
+		FromConfigURL(server.URL+"/config.json", Auto),
+		WithURLCA(ca),
+		WithURLClientCert(certFile, keyFile),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Level != "mtls" {
+		t.Fatalf("unexpected config: %+v", result)
+	}
+}
+
+func TestWatchURLRenewedCertificate(t *testing.T) {
+
+	dir := t.TempDir()
+	certFile, keyFile, oldCert := writeTestCertificate(t, dir, "old")
+	renewedCert, renewedKey, newCert := writeTestCertificate(t, t.TempDir(), "new")
+
+	clients := x509.NewCertPool()
+	clients.AddCert(oldCert)
+	clients.AddCert(newCert)
+
+	// the level served is the name of the client certificate used
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fmt.Sprintf(`{"Level": %q}`, r.TLS.PeerCertificates[0].Subject.CommonName)))
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clients,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	ca := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	initial, updates, err := Watch[remoteStruct](ctx,
+		FromConfigURL(server.URL+"/config.json", Auto),
+		WithURLCA(ca),
+		WithURLClientCert(certFile, keyFile),
+		WithPollInterval(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if initial.Level != "old" {
+		t.Fatalf("unexpected initial config: %+v", initial)
+	}
+
+	for from, to := range map[string]string{renewedCert: certFile, renewedKey: keyFile} {
+		if err := os.Rename(from, to); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case update := <-updates:
+		if update.Level != "new" {
+			t.Fatalf("unexpected update: %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the renewed certificate to be used")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func mustLoadKeyPair(t *testing.T, certFile, keyFile string) tls.Certificate {
+	t.Helper()
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+// versionedServer serves {"Level": <level>} with an ETag of its version, blocking queries with ?wait= are held until the level changes
+type versionedServer struct {
+	mu      sync.Mutex
+	version int
+	level   string
+	changed chan struct{}
+
+	waits atomic.Int32
+}
+
+func (v *versionedServer) set(level string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.version++
+	v.level = level
+	close(v.changed)
+	v.changed = make(chan struct{})
+}
+
+func (v *versionedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	etag, level, changed := fmt.Sprintf(`"%d"`, v.version), v.level, v.changed
+	v.mu.Unlock()
+
+	if r.Header.Get("If-None-Match") == etag {
+		wait, err := time.ParseDuration(r.URL.Query().Get("wait"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		v.waits.Add(1)
+		select {
+		case <-changed:
+			v.ServeHTTP(w, r)
+		case <-time.After(wait):
+			w.WriteHeader(http.StatusNotModified)
+		case <-r.Context().Done():
+		}
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Write([]byte(fmt.Sprintf(`{"Level": %q}`, level)))
+}
+
+func TestWatchURL(t *testing.T) {
+
+	for _, longPoll := range []bool{false, true} {
+		v := &versionedServer{level: "info", changed: make(chan struct{})}
+		server := httptest.NewServer(v)
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		opts := []OptionFunc{FromConfigURL(server.URL+"/config.json", Auto)}
+		if longPoll {
+			// polling is slower than the test timeout, so only a blocking query can see the change in time
+			opts = append(opts, WithURLLongPoll("wait", 30*time.Second), WithPollInterval(time.Hour))
+		} else {
+			opts = append(opts, WithPollInterval(10*time.Millisecond))
+		}
+
+		initial, updates, err := Watch[remoteStruct](ctx, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if initial.Level != "info" {
+			t.Fatalf("unexpected initial config: %+v", initial)
+		}
+
+		v.set("debug")
+
+		select {
+		case update := <-updates:
+			if update.Level != "debug" {
+				t.Fatalf("unexpected update: %+v", update)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for url update, long poll: %v", longPoll)
+		}
+
+		if longPoll && v.waits.Load() == 0 {
+			t.Fatal("expected a blocking query to be made")
+		}
+
+		cancel()
+	}
+}
+
+func TestWatchURLReloadWarnings(t *testing.T) {
+
+	type aliasedStruct struct {
+		Level string `confy_alias:"lvl;deprecated"`
+	}
+
+	var body atomic.Value
+	body.Store(`{"Level": "info"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloadErrors := make(chan error, 10)
+	_, updates, err := Watch[aliasedStruct](ctx,
+		FromConfigURL(server.URL+"/config.json", Auto),
+		WithPollInterval(10*time.Millisecond),
+		WithReloadErrorHandler(func(err error) {
+			reloadErrors <- err
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body.Store(`{"lvl": "debug"}`)
+
+	select {
+	case err := <-reloadErrors:
+		if !strings.Contains(err.Error(), "deprecated") {
+			t.Fatalf("expected the deprecation warning of the reload, got: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the reload warning")
+	}
+
+	select {
+	case update := <-updates:
+		if update.Level != "debug" {
+			t.Fatalf("unexpected update: %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the reloaded config")
+	}
+}
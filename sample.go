@@ -0,0 +1,304 @@
+package confy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// SampleDescription describes a single key of a generated sample config, GenerateSampleDescriptions writes these for json files as json has no comments
+type SampleDescription struct {
+	Description string   `json:"description,omitempty"`
+	Allowed     []string `json:"allowed,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Sensitive   bool     `json:"sensitive,omitempty"`
+	// Example is an example entry for slices and maps, which are empty in the sample
+	Example any `json:"example,omitempty"`
+}
+
+// GenerateSample writes a config file containing every key of T with its default (zero) value, using the same keys confy reads (honouring confy:"name" renames and WithNaming)
+// For yaml and toml the confy_description, confy_oneof and modifiers of each field are written as comments, along with a commented out example entry for slices and maps
+// json has no comments, use GenerateSampleDescriptions to write them to a separate file
+func GenerateSample[T any](configType ConfigType, w io.Writer, suppliedOptions ...OptionFunc) error {
+	var a T
+	if reflect.TypeOf(a).Kind() != reflect.Struct {
+		panic("GenerateSample(...) only supports configs of Struct type")
+	}
+
+	o, err := sampleOptions(suppliedOptions)
+	if err != nil {
+		return err
+	}
+
+	original := reflect.TypeOf(a)
+	modified := encodableType(newConfigLoader[T](o).createModifiedType(original), original, false)
+
+	sample := reflect.New(modified)
+	emptyCollections(sample.Elem())
+
+	switch configType {
+	case Json:
+		output, err := json.MarshalIndent(sample.Interface(), "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", output)
+		return err
+	case Yaml:
+		var doc yaml.Node
+		if err := doc.Encode(sample.Interface()); err != nil {
+			return err
+		}
+
+		for _, field := range getFields(true, &a) {
+			keyPath := strings.Split(fileKeyPath(modified, field.path, "yaml"), ".")
+
+			key := findYAMLKey(&doc, keyPath)
+			if key == nil {
+				continue
+			}
+
+			_, modifiedField := getField(reflect.New(modified).Interface(), field.path)
+			key.HeadComment = sampleComment(field.tag, keyPath[len(keyPath)-1], modifiedField.Type, Yaml)
+		}
+
+		output, err := marshalYAML(&doc)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(output)
+		return err
+	case Toml:
+		commented := reflect.New(commentedType(modified, original))
+		emptyCollections(commented.Elem())
+
+		output, err := toml.Marshal(commented.Interface())
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(output)
+		return err
+	default:
+		return fmt.Errorf("cannot generate sample config as %q, must be one of %s, %s or %s", configType, Json, Yaml, Toml)
+	}
+}
+
+// GenerateSampleDescriptions writes a json object of dotted config key -> SampleDescription, to accompany a json sample from GenerateSample
+func GenerateSampleDescriptions[T any](w io.Writer, suppliedOptions ...OptionFunc) error {
+	var a T
+	if reflect.TypeOf(a).Kind() != reflect.Struct {
+		panic("GenerateSampleDescriptions(...) only supports configs of Struct type")
+	}
+
+	o, err := sampleOptions(suppliedOptions)
+	if err != nil {
+		return err
+	}
+
+	modified := encodableType(newConfigLoader[T](o).createModifiedType(reflect.TypeOf(a)), reflect.TypeOf(a), false)
+
+	descriptions := map[string]SampleDescription{}
+	for _, field := range getFields(true, &a) {
+		description := SampleDescription{
+			Description: field.tag.Get(confyDescriptionTag),
+			Allowed:     getOneOf(field.tag),
+			Required:    hasModifier(field.tag, confyTag, "required"),
+			Sensitive:   hasModifier(field.tag, confyTag, "sensitive"),
+		}
+
+		_, modifiedField := getField(reflect.New(modified).Interface(), field.path)
+		if example, ok := exampleValue(modifiedField.Type); ok {
+			description.Example = example.Interface()
+		}
+
+		if reflect.ValueOf(description).IsZero() {
+			continue
+		}
+
+		descriptions[fileKeyPath(modified, field.path, "json")] = description
+	}
+
+	output, err := json.MarshalIndent(descriptions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", output)
+	return err
+}
+
+func sampleOptions(suppliedOptions []OptionFunc) (*options, error) {
+	o := &options{
+		currentlySet: make(map[preference]bool),
+	}
+
+	var errs []error
+	for _, optFunc := range suppliedOptions {
+		if err := optFunc(o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return o, errors.Join(errs...)
+}
+
+// sampleComment builds the comment for a key, its description, constraints and an example entry if it is a slice or map
+func sampleComment(tag reflect.StructTag, key string, modifiedType reflect.Type, configType ConfigType) string {
+	var lines []string
+	if description := tag.Get(confyDescriptionTag); description != "" {
+		lines = append(lines, description)
+	}
+
+	var details []string
+	if allowed := getOneOf(tag); len(allowed) > 0 {
+		details = append(details, "one of: "+strings.Join(allowed, ", "))
+	}
+
+	if hasModifier(tag, confyTag, "required") {
+		details = append(details, "required")
+	}
+
+	if hasModifier(tag, confyTag, "sensitive") {
+		details = append(details, "sensitive")
+	}
+
+	if len(details) > 0 {
+		lines = append(lines, strings.Join(details, ", "))
+	}
+
+	if example, ok := exampleValue(modifiedType); ok {
+		var (
+			output []byte
+			err    error
+		)
+
+		entry := map[string]any{key: example.Interface()}
+		switch configType {
+		case Yaml:
+			output, err = marshalYAML(entry)
+		case Toml:
+			output, err = toml.Marshal(entry)
+		}
+
+		if err == nil && len(output) > 0 {
+			lines = append(lines, "example:", strings.TrimRight(string(output), "\n"))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func marshalYAML(v any) ([]byte, error) {
+	var b bytes.Buffer
+
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// emptyCollections sets nil slices and maps within v to empty ones, so that they are written as [] and {} rather than null or not at all
+func emptyCollections(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				emptyCollections(v.Field(i))
+			}
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	}
+}
+
+// exampleValue returns a slice or map with a single zero value entry, ok is false for other types
+func exampleValue(t reflect.Type) (reflect.Value, bool) {
+	switch t.Kind() {
+	case reflect.Slice:
+		return reflect.MakeSlice(t, 1, 1), true
+	case reflect.Map:
+		key := reflect.New(t.Key()).Elem()
+		if key.Kind() == reflect.String {
+			key.SetString("key")
+		}
+
+		example := reflect.MakeMap(t)
+		example.SetMapIndex(key, reflect.New(t.Elem()).Elem())
+		return example, true
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+// findYAMLKey returns the key node for the path of keys within doc
+func findYAMLKey(doc *yaml.Node, keyPath []string) *yaml.Node {
+	node := doc
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	var key *yaml.Node
+	for _, part := range keyPath {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		found := false
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == part {
+				key, node = node.Content[i], node.Content[i+1]
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil
+		}
+	}
+
+	return key
+}
+
+// commentedType adds toml comment tags to modified, based on the confy tags within original
+func commentedType(modified, original reflect.Type) reflect.Type {
+	if modified.Kind() != reflect.Struct || encodesItself(original) {
+		return modified
+	}
+
+	fields := make([]reflect.StructField, modified.NumField())
+	for i := range fields {
+		fields[i] = modified.Field(i)
+		fields[i].Type = commentedType(fields[i].Type, original.Field(i).Type)
+
+		comment := sampleComment(original.Field(i).Tag, fileKey(fields[i], "toml"), fields[i].Type, Toml)
+		if comment != "" {
+			fields[i].Tag = reflect.StructTag(fmt.Sprintf(`%s comment:%q`, fields[i].Tag, comment))
+		}
+	}
+
+	return reflect.StructOf(fields)
+}
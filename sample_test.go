@@ -0,0 +1,75 @@
+package confy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type sampleStruct struct {
+	Name     string            `confy:"name" confy_description:"name of the app"`
+	Level    string            `confy:"level;required" confy_oneof:"debug,info"`
+	Hosts    []string          `confy:"hosts" confy_description:"hosts to connect to"`
+	Labels   map[string]string `confy:"labels"`
+	Database struct {
+		Port     int    `confy:"port" confy_description:"port to connect to"`
+		Password string `confy:"password;sensitive"`
+	} `confy:"database" confy_description:"database settings"`
+}
+
+func TestGenerateSample(t *testing.T) {
+
+	expected := map[ConfigType][]string{
+		Yaml: {"# name of the app\nname: \"\"", "# one of: debug, info, required\nlevel: \"\"", "# example:\n# hosts:\n#   - \"\"\nhosts: []", "# database settings\ndatabase:", "  # port to connect to\n  port: 0", "  # sensitive\n  password: \"\""},
+		Toml: {"# name of the app\nname = ''", "# one of: debug, info, required\nlevel = ''", "# example:\n# hosts = ['']\nhosts = []", "# database settings\n[database]", "# port to connect to\nport = 0"},
+		Json: {`"name": ""`, `"hosts": []`, `"labels": {}`, `"port": 0`},
+	}
+
+	for configType, contains := range expected {
+		var b bytes.Buffer
+		if err := GenerateSample[sampleStruct](configType, &b); err != nil {
+			t.Fatal(configType, err)
+		}
+
+		for _, c := range contains {
+			if !strings.Contains(b.String(), c) {
+				t.Fatalf("%s sample missing %q:\n%s", configType, c, b.String())
+			}
+		}
+
+		// every key in the sample must be one that confy reads, the placeholder values are not expected to pass validation
+		if _, _, err := Config[sampleStruct](FromConfigBytes(b.Bytes(), configType), WithStrictParsing()); err != nil && !strings.Contains(err.Error(), "invalid configuration") {
+			t.Fatalf("%s sample could not be loaded: %s\n%s", configType, err, b.String())
+		}
+	}
+
+	if err := GenerateSample[sampleStruct](Auto, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected Auto to be an error")
+	}
+}
+
+func TestGenerateSampleDescriptions(t *testing.T) {
+
+	var b bytes.Buffer
+	if err := GenerateSampleDescriptions[sampleStruct](&b); err != nil {
+		t.Fatal(err)
+	}
+
+	var descriptions map[string]SampleDescription
+	if err := json.Unmarshal(b.Bytes(), &descriptions); err != nil {
+		t.Fatal(err)
+	}
+
+	if descriptions["database.port"].Description != "port to connect to" || !descriptions["database.password"].Sensitive {
+		t.Fatalf("unexpected nested descriptions: %+v", descriptions)
+	}
+
+	if !descriptions["level"].Required || strings.Join(descriptions["level"].Allowed, ",") != "debug,info" {
+		t.Fatalf("unexpected level description: %+v", descriptions["level"])
+	}
+
+	if descriptions["hosts"].Example == nil || descriptions["labels"].Example == nil {
+		t.Fatalf("expected examples for slices and maps: %+v", descriptions)
+	}
+}
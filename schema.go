@@ -0,0 +1,180 @@
+package confy
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+type jsonSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type   any    `json:"type,omitempty"`
+	Format string `json:"format,omitempty"`
+
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"`
+
+	Items *jsonSchema `json:"items,omitempty"`
+
+	Enum    []any `json:"enum,omitempty"`
+	Minimum *int  `json:"minimum,omitempty"`
+	Default any   `json:"default,omitempty"`
+
+	Deprecated bool `json:"deprecated,omitempty"`
+	WriteOnly  bool `json:"writeOnly,omitempty"`
+}
+
+// JSONSchema returns a draft 2020-12 JSON schema describing the config file for T, for editor completion and validation (e.g yaml-language-server or taplo)
+// Keys follow the confy naming (confy:"name" renames and WithNaming), descriptions come from confy_description, confy_oneof becomes an enum and fields with the required modifier are required.
+// Aliases are included as deprecated properties if they are marked as such, and if WithStrictParsing is given unknown keys are rejected with additionalProperties: false
+func JSONSchema[T any](suppliedOptions ...OptionFunc) ([]byte, error) {
+	var a T
+	if reflect.TypeOf(a).Kind() != reflect.Struct {
+		panic("JSONSchema(...) only supports configs of Struct type")
+	}
+
+	o, err := sampleOptions(suppliedOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	original := reflect.TypeOf(a)
+	modified := encodableType(newConfigLoader[T](o).createModifiedType(original), original, false)
+
+	schema := schemaFor(modified, original, o.config.strictParsing)
+	schema.Schema = jsonSchemaDraft
+	schema.Title = original.Name()
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaFor describes original, modified is the type created from it by createModifiedType and holds the file keys
+func schemaFor(modified, original reflect.Type, strict bool) *jsonSchema {
+	for original.Kind() == reflect.Ptr {
+		original = original.Elem()
+		modified = modified.Elem()
+	}
+
+	switch {
+	case original == reflect.TypeOf(time.Duration(0)):
+		// durations may be written as "5s" or as nanoseconds
+		return &jsonSchema{Type: []string{"string", "integer"}}
+	case original == reflect.TypeOf(time.Time{}):
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	case encodesItself(original):
+		return &jsonSchema{Type: "string"}
+	}
+
+	switch original.Kind() {
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		minimum := 0
+		return &jsonSchema{Type: "integer", Minimum: &minimum}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: "array", Items: schemaFor(modified.Elem(), original.Elem(), strict)}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: schemaFor(modified.Elem(), original.Elem(), strict)}
+	case reflect.Struct:
+		schema := &jsonSchema{
+			Type:       "object",
+			Properties: map[string]*jsonSchema{},
+		}
+
+		if strict {
+			schema.AdditionalProperties = false
+		}
+
+		for i := 0; i < original.NumField(); i++ {
+			field := original.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			key := fileKey(modified.Field(i), "yaml")
+			if key == "-" {
+				continue
+			}
+
+			property := schemaFor(modified.Field(i).Type, field.Type, strict)
+			property.Description = field.Tag.Get(confyDescriptionTag)
+			property.WriteOnly = hasModifier(field.Tag, confyTag, "sensitive")
+
+			if allowed := getOneOf(field.Tag); len(allowed) > 0 {
+				target := property
+				if target.Items != nil {
+					target = target.Items
+				}
+
+				for _, value := range allowed {
+					target.Enum = append(target.Enum, enumValue(value, field.Type))
+				}
+			}
+
+			required := hasModifier(field.Tag, confyTag, "required")
+			if required {
+				schema.Required = append(schema.Required, key)
+			}
+
+			// unset scalar fields are left as their zero value, which is only a useful default if the field is optional and the zero value is allowed
+			scalar := property.Properties == nil && property.Items == nil && property.AdditionalProperties == nil
+			if scalar && !required && property.Enum == nil && field.Type.Kind() != reflect.Ptr && !encodesItself(field.Type) {
+				property.Default = reflect.Zero(field.Type).Interface()
+			}
+
+			schema.Properties[key] = property
+
+			aliases, deprecated := getAliases(field.Tag)
+			for _, alias := range aliases {
+				aliasProperty := *property
+				aliasProperty.Deprecated = deprecated
+				schema.Properties[alias] = &aliasProperty
+			}
+		}
+
+		return schema
+	default:
+		return &jsonSchema{}
+	}
+}
+
+// enumValue converts a confy_oneof value to the json type of t, so that numbers and bools are not compared as strings
+func enumValue(value string, t reflect.Type) any {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	if encodesItself(t) {
+		return value
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, err := parseBool(value); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,90 @@
+package confy
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type schemaStruct struct {
+	Name     string        `confy:"name" confy_description:"name of the app"`
+	Level    int           `confy:"level;required" confy_oneof:"1,2,3"`
+	Tags     []string      `confy:"tags" confy_oneof:"a,b"`
+	Timeout  time.Duration `confy:"timeout"`
+	Started  time.Time     `confy:"started"`
+	Port     uint16        `confy:"port" confy_alias:"listen_port;deprecated"`
+	Labels   map[string]int
+	Database struct {
+		Password string `confy:"password;sensitive"`
+	} `confy:"database"`
+}
+
+func TestJSONSchema(t *testing.T) {
+
+	output, err := JSONSchema[schemaStruct](WithStrictParsing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema jsonSchema
+	if err := json.Unmarshal(output, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if schema.Schema != jsonSchemaDraft || schema.Type != "object" || schema.AdditionalProperties != false {
+		t.Fatalf("unexpected root schema:\n%s", output)
+	}
+
+	if len(schema.Required) != 1 || schema.Required[0] != "level" {
+		t.Fatalf("expected level to be required: %v", schema.Required)
+	}
+
+	name := schema.Properties["name"]
+	if name == nil || name.Description != "name of the app" || name.Type != "string" || name.Default != "" {
+		t.Fatalf("unexpected name property: %+v", name)
+	}
+
+	level := schema.Properties["level"]
+	if level.Type != "integer" || len(level.Enum) != 3 || level.Enum[0] != float64(1) || level.Default != nil {
+		t.Fatalf("expected level to be an integer enum without a default: %+v", level)
+	}
+
+	if tags := schema.Properties["tags"]; tags.Type != "array" || len(tags.Items.Enum) != 2 {
+		t.Fatalf("expected tags enum to apply to the items: %+v", tags)
+	}
+
+	if started := schema.Properties["started"]; started.Format != "date-time" {
+		t.Fatalf("expected started to be a date-time: %+v", started)
+	}
+
+	if port := schema.Properties["port"]; port.Minimum == nil || *port.Minimum != 0 {
+		t.Fatalf("expected unsigned port to have a minimum: %+v", port)
+	}
+
+	if alias := schema.Properties["listen_port"]; alias == nil || !alias.Deprecated {
+		t.Fatalf("expected deprecated alias property: %+v", alias)
+	}
+
+	if labels := schema.Properties["Labels"]; labels.Type != "object" {
+		t.Fatalf("expected map to be an object: %+v", labels)
+	}
+
+	database := schema.Properties["database"]
+	if database.AdditionalProperties != false || !database.Properties["password"].WriteOnly {
+		t.Fatalf("unexpected nested database schema: %+v", database)
+	}
+
+	output, err = JSONSchema[schemaStruct]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema = jsonSchema{}
+	if err := json.Unmarshal(output, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if schema.AdditionalProperties != nil {
+		t.Fatalf("additional properties should be allowed without strict parsing:\n%s", output)
+	}
+}
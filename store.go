@@ -0,0 +1,178 @@
+package confy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds the current configuration, it is safe to Load from any goroutine while another Swaps in a new configuration
+// Subscribers are told which field paths changed, paths are dotted confy paths (e.g "database.port") as in Change.Path, without map keys or slice indexes
+type Store[T any] struct {
+	current atomic.Pointer[T]
+
+	// swapping serialises Swap so that subscribers see changes in order
+	swapping sync.Mutex
+
+	mu          sync.Mutex
+	subscribers map[int]*subscriber[T]
+	nextID      int
+}
+
+type subscriber[T any] struct {
+	// path is empty for subscribers to every change
+	path string
+	fn   func(old, new T, changed []string)
+}
+
+// NewStore returns a Store holding initial
+func NewStore[T any](initial T) *Store[T] {
+	s := &Store[T]{
+		subscribers: map[int]*subscriber[T]{},
+	}
+	s.current.Store(&initial)
+
+	return s
+}
+
+// WatchStore loads the configuration with Watch and returns a Store that is updated with every reload until ctx is done
+func WatchStore[T any](ctx context.Context, suppliedOptions ...OptionFunc) (*Store[T], error) {
+	initial, updates, err := Watch[T](ctx, suppliedOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewStore(initial)
+	go func() {
+		for update := range updates {
+			s.Swap(update)
+		}
+	}()
+
+	return s, nil
+}
+
+// Load returns the current configuration
+func (s *Store[T]) Load() T {
+	return *s.current.Load()
+}
+
+// Swap replaces the current configuration with next and returns the paths that changed
+// If anything changed, subscribers are called in the order they subscribed before Swap returns, subscribers must not call Swap
+func (s *Store[T]) Swap(next T) (changed []string) {
+	s.swapping.Lock()
+	defer s.swapping.Unlock()
+
+	old := *s.current.Swap(&next)
+
+	changed = changedFields(diffValues(reflect.ValueOf(old), reflect.ValueOf(next)))
+	if len(changed) == 0 {
+		return nil
+	}
+
+	logger.Info("config store updated", "changed", changed)
+
+	s.mu.Lock()
+	ids := make([]int, 0, len(s.subscribers))
+	for id := range s.subscribers {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	subscribers := make([]*subscriber[T], 0, len(ids))
+	for _, id := range ids {
+		subscribers = append(subscribers, s.subscribers[id])
+	}
+	s.mu.Unlock()
+
+	for _, sub := range subscribers {
+		if sub.path != "" && !pathsOverlap(sub.path, changed) {
+			continue
+		}
+
+		sub.fn(old, next, changed)
+	}
+
+	return changed
+}
+
+// Subscribe calls fn with the old and new configuration, and the paths that changed, whenever Swap changes the configuration
+// The returned function removes the subscription
+func (s *Store[T]) Subscribe(fn func(old, new T, changed []string)) (unsubscribe func()) {
+	return s.subscribe(&subscriber[T]{fn: fn})
+}
+
+// SubscribePath is like Subscribe, but fn is only called when the field at path (or a field within it) changes
+// path is the dotted confy path of a field, e.g "log.level", or "rate_limits" for every field within a struct
+func (s *Store[T]) SubscribePath(path string, fn func(old, new T, changed []string)) (unsubscribe func(), err error) {
+	var a T
+	if err := checkFieldPath(reflect.TypeOf(a), path); err != nil {
+		return nil, err
+	}
+
+	return s.subscribe(&subscriber[T]{path: path, fn: fn}), nil
+}
+
+func (s *Store[T]) subscribe(sub *subscriber[T]) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.subscribers[id] = sub
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.subscribers, id)
+	}
+}
+
+// pathsOverlap reports whether path is one of changed, is within one of them or contains one of them
+func pathsOverlap(path string, changed []string) bool {
+	for _, c := range changed {
+		if c == path || strings.HasPrefix(c, path+".") || strings.HasPrefix(path, c+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkFieldPath checks that the dotted confy path names an exported field within t
+func checkFieldPath(t reflect.Type, path string) error {
+	if path == "" {
+		return fmt.Errorf("field path must not be empty")
+	}
+
+	current := t
+	for _, name := range strings.Split(path, ".") {
+		for current.Kind() == reflect.Ptr {
+			current = current.Elem()
+		}
+
+		if current.Kind() != reflect.Struct {
+			return fmt.Errorf("field path %q not found in %s", path, t)
+		}
+
+		found := false
+		for i := 0; i < current.NumField(); i++ {
+			field := current.Field(i)
+			if field.IsExported() && resolveName(field) == name {
+				current, found = field.Type, true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("field path %q not found in %s", path, t)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package confy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type storeStruct struct {
+	Log struct {
+		Level string `confy:"level"`
+	} `confy:"log"`
+	RateLimit *struct {
+		Requests int `confy:"requests"`
+	} `confy:"rate_limit"`
+	Hosts []string `confy:"hosts"`
+}
+
+func TestStoreSubscribe(t *testing.T) {
+
+	var initial storeStruct
+	initial.Log.Level = "info"
+
+	s := NewStore(initial)
+
+	var all [][]string
+	unsubscribe := s.Subscribe(func(old, new storeStruct, changed []string) {
+		all = append(all, changed)
+	})
+
+	var levels []string
+	if _, err := s.SubscribePath("log", func(old, new storeStruct, changed []string) {
+		levels = append(levels, old.Log.Level+"->"+new.Log.Level)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var limits int
+	if _, err := s.SubscribePath("rate_limit.requests", func(old, new storeStruct, changed []string) {
+		limits++
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	next := s.Load()
+	next.Log.Level = "debug"
+	next.Hosts = []string{"a"}
+	if changed := s.Swap(next); !slices.Equal(changed, []string{"log.level", "hosts"}) {
+		t.Fatalf("unexpected changed paths: %v", changed)
+	}
+
+	if s.Load().Log.Level != "debug" {
+		t.Fatal("Load did not return the swapped config")
+	}
+
+	// nothing changed, so no subscriber should be called
+	if changed := s.Swap(s.Load()); changed != nil {
+		t.Fatalf("expected no changes, got: %v", changed)
+	}
+
+	next = s.Load()
+	next.RateLimit = &struct {
+		Requests int `confy:"requests"`
+	}{Requests: 10}
+	s.Swap(next)
+
+	next = s.Load()
+	next.RateLimit = &struct {
+		Requests int `confy:"requests"`
+	}{Requests: 20}
+	if changed := s.Swap(next); !slices.Equal(changed, []string{"rate_limit.requests"}) {
+		t.Fatalf("unexpected changed paths: %v", changed)
+	}
+
+	unsubscribe()
+	next = s.Load()
+	next.Hosts = nil
+	s.Swap(next)
+
+	if len(all) != 3 || !slices.Equal(all[2], []string{"rate_limit.requests"}) {
+		t.Fatalf("unexpected changes for subscriber: %v", all)
+	}
+
+	if !slices.Equal(levels, []string{"info->debug"}) {
+		t.Fatalf("unexpected changes for log subscriber: %v", levels)
+	}
+
+	if limits != 2 {
+		t.Fatalf("expected rate_limit.requests subscriber to be called twice, was called %d times", limits)
+	}
+
+	if _, err := s.SubscribePath("log.missing", func(old, new storeStruct, changed []string) {}); err == nil {
+		t.Fatal("expected unknown path to fail")
+	}
+
+	if _, err := s.SubscribePath("Log.Level", func(old, new storeStruct, changed []string) {}); err == nil {
+		t.Fatal("expected go field names to fail, paths are confy paths")
+	}
+}
+
+func TestStoreConcurrent(t *testing.T) {
+
+	s := NewStore(storeStruct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				next := s.Load()
+				next.Hosts = append(slices.Clone(next.Hosts), "host")
+				s.Swap(next)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = s.Load().Log.Level
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWatchStore(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"log": {"level": "info"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := WatchStore[storeStruct](ctx, FromConfigFile(path, Auto), WithPollInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan []string, 1)
+	if _, err := s.SubscribePath("log.level", func(old, new storeStruct, changed []string) {
+		changes <- changed
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"log": {"level": "debug"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case changed := <-changes:
+		if !slices.Equal(changed, []string{"log.level"}) {
+			t.Fatalf("unexpected changed paths: %v", changed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for store update")
+	}
+
+	if s.Load().Log.Level != "debug" {
+		t.Fatalf("store was not updated: %+v", s.Load())
+	}
+}
@@ -29,6 +29,12 @@ const (
 const (
 	confyTag            = "confy"
 	confyDescriptionTag = "confy_description"
+	confyAliasTag       = "confy_alias"
+	confyShortTag       = "confy_short"
+	confyCmdTag         = "confy_cmd"
+	confyArgTag         = "confy_arg"
+	confyArgsTag        = "confy_args"
+	confyOneOfTag       = "confy_oneof"
 )
 
 const (
@@ -0,0 +1,103 @@
+package confy
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Validator is implemented by configs that check themselves, Validate is called once the required modifier and confy_oneof constraints pass
+// Config returns the error, and Watch does not publish a reloaded config that fails it
+type Validator interface {
+	Validate() error
+}
+
+// getOneOf returns the values allowed by the confy_oneof tag, nil if any value is allowed
+func getOneOf(tag reflect.StructTag) []string {
+	value, ok := tag.Lookup(confyOneOfTag)
+	if !ok || value == "" {
+		return nil
+	}
+
+	var allowed []string
+	for _, v := range strings.Split(value, ",") {
+		allowed = append(allowed, strings.TrimSpace(v))
+	}
+
+	return allowed
+}
+
+// valueString formats v the same way it would be written on the command line
+func valueString(v reflect.Value) string {
+	if v.CanAddr() {
+		if marshaler, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			text, err := marshaler.MarshalText()
+			if err == nil {
+				return string(text)
+			}
+		}
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// validate checks the required modifier and confy_oneof tag against the fields the sources set, fields of unselected commands are ignored
+// A value a source set is checked even if it is the zero value (e.g -flag=false or PORT=0). If result implements Validator it is called last
+func (o *options) validate(result any) error {
+	var errs []error
+	for _, field := range getFields(false, result) {
+		if o.commands.inactive(field.path) {
+			continue
+		}
+
+		path := strings.Join(field.path, ".")
+		if !o.fieldsSet[path] {
+			if hasModifier(field.tag, confyTag, "required") {
+				errs = append(errs, fmt.Errorf("%s is required but was not set", path))
+			}
+			continue
+		}
+
+		allowed := getOneOf(field.tag)
+		if allowed == nil {
+			continue
+		}
+
+		value := field.value
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+
+		values := []reflect.Value{value}
+		if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+			values = values[:0]
+			for i := 0; i < value.Len(); i++ {
+				values = append(values, value.Index(i))
+			}
+		}
+
+		for _, v := range values {
+			if s := valueString(v); !slices.Contains(allowed, s) {
+				errs = append(errs, fmt.Errorf("%s has value %q, must be one of %s", path, maskSensitive(s, field.tag), strings.Join(allowed, ", ")))
+			}
+		}
+	}
+
+	if validator, ok := result.(Validator); ok && len(errs) == 0 {
+		if err := validator.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: invalid configuration: %w", errFatal, err)
+	}
+
+	return nil
+}
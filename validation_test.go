@@ -0,0 +1,132 @@
+package confy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type validationStruct struct {
+	Level    string   `confy:"level" confy_oneof:"debug,info"`
+	Features []string `confy:"features" confy_oneof:"metrics,tracing"`
+	Token    string   `confy:"token;sensitive" confy_oneof:"a,b"`
+	Database struct {
+		Port int     `confy:";required"`
+		Name *string `confy:";required"`
+	}
+
+	Serve struct {
+		Listen string `confy:";required"`
+	} `confy_cmd:"serve"`
+}
+
+func TestValidationRequired(t *testing.T) {
+
+	_, _, err := Config[validationStruct](FromConfigBytes([]byte(`{"level": "info"}`), Json))
+	if err == nil || !strings.Contains(err.Error(), "Database.Port is required") || !strings.Contains(err.Error(), "Database.Name is required") {
+		t.Fatalf("expected missing required fields to be an error, got: %v", err)
+	}
+
+	if strings.Contains(err.Error(), "Listen") {
+		t.Fatalf("required fields of unselected commands should be ignored, got: %v", err)
+	}
+
+	config, _, err := Config[validationStruct](FromConfigBytes([]byte(`{"Database": {"Port": 5432, "Name": "users"}}`), Json))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Database.Port != 5432 || *config.Database.Name != "users" {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+}
+
+func TestValidationOneOf(t *testing.T) {
+
+	required := FromConfigBytes([]byte(`{"Database": {"Port": 5432, "Name": "users"}}`), Json)
+
+	_, _, err := Config[validationStruct](required, FromCli(CLIDelimiter), WithArgs([]string{"-level", "trace"}))
+	if err == nil || !strings.Contains(err.Error(), `Level has value "trace", must be one of debug, info`) {
+		t.Fatalf("expected value outside of confy_oneof to be an error, got: %v", err)
+	}
+
+	_, _, err = Config[validationStruct](required, FromCli(CLIDelimiter), WithArgs([]string{"-features", "metrics,profiling"}))
+	if err == nil || !strings.Contains(err.Error(), `"profiling", must be one of metrics, tracing`) {
+		t.Fatalf("expected each slice element to be checked, got: %v", err)
+	}
+
+	_, _, err = Config[validationStruct](required, FromCli(CLIDelimiter), WithArgs([]string{"-token", "secret"}))
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Fatalf("expected sensitive value to be masked in the error, got: %v", err)
+	}
+
+	config, _, err := Config[validationStruct](required, FromCli(CLIDelimiter), WithArgs([]string{"-level", "debug", "-features", "tracing"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Level != "debug" || len(config.Features) != 1 {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+}
+
+func TestValidationZeroValues(t *testing.T) {
+
+	type zeroStruct struct {
+		Enabled bool   `confy:"enabled;required"`
+		Port    int    `confy:"port;required"`
+		Mode    string `confy:"mode" confy_oneof:"a,b"`
+	}
+
+	// values set on purpose to the zero value satisfy required
+	for name, source := range map[string][]OptionFunc{
+		"cli":  {FromCli(CLIDelimiter), WithArgs([]string{"-enabled=false", "-port=0"})},
+		"env":  {FromEnvs(ENVDelimiter), WithEnviron(map[string]string{"enabled": "false", "port": "0"})},
+		"file": {FromConfigBytes([]byte(`{"enabled": false, "port": 0}`), Json)},
+		"yaml": {FromConfigBytes([]byte("enabled: false\nport: 0\n"), Yaml)},
+		"toml": {FromConfigBytes([]byte("enabled = false\nport = 0\n"), Toml)},
+	} {
+		if _, _, err := Config[zeroStruct](source...); err != nil {
+			t.Fatalf("%s: expected zero values to satisfy required, got: %v", name, err)
+		}
+	}
+
+	_, _, err := Config[zeroStruct](FromConfigBytes([]byte(`{"enabled": false, "port": 0, "mode": ""}`), Json))
+	if err == nil || !strings.Contains(err.Error(), `Mode has value "", must be one of a, b`) {
+		t.Fatalf("expected an empty value that was set to be checked against confy_oneof, got: %v", err)
+	}
+}
+
+type validatorStruct struct {
+	Level string `confy_oneof:"debug,info"`
+	Port  int
+}
+
+func (v validatorStruct) Validate() error {
+	if v.Port > 65535 {
+		return errors.New("port out of range")
+	}
+	return nil
+}
+
+func TestValidationValidator(t *testing.T) {
+
+	_, _, err := Config[validatorStruct](FromConfigBytes([]byte(`{"Level": "info", "Port": 70000}`), Json))
+	if err == nil || !strings.Contains(err.Error(), "port out of range") {
+		t.Fatalf("expected Validate error, got: %v", err)
+	}
+
+	_, _, err = Config[validatorStruct](FromConfigBytes([]byte(`{"Level": "trace", "Port": 70000}`), Json))
+	if err == nil || strings.Contains(err.Error(), "port out of range") {
+		t.Fatalf("expected Validate to only run once the tag constraints pass, got: %v", err)
+	}
+
+	config, _, err := Config[validatorStruct](FromConfigBytes([]byte(`{"Level": "info", "Port": 80}`), Json))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Port != 80 {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+}
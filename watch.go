@@ -0,0 +1,366 @@
+package confy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// watchDebounce is how long Watch waits after a filesystem event before reloading, editors often write a file in several steps
+	watchDebounce = 100 * time.Millisecond
+
+	// defaultPollInterval is used when filesystem notifications are not available
+	defaultPollInterval = 2 * time.Second
+)
+
+type watchOptions struct {
+	onError      func(error)
+	pollInterval time.Duration
+
+	onSIGHUP bool
+
+	staticPolicy StaticPolicy
+}
+
+// StaticPolicy decides what Watch does when a reload changes a field with the static modifier
+type StaticPolicy int
+
+const (
+	// RejectStaticChanges rejects the reloaded config, the error passed to the WithReloadErrorHandler handler names each changed static field
+	RejectStaticChanges StaticPolicy = iota
+	// KeepStaticFields keeps the current value of each changed static field with a warning, and applies the rest of the reloaded config
+	KeepStaticFields
+)
+
+func (w watchOptions) reloadError(err error) {
+	if w.onError != nil {
+		w.onError(err)
+		return
+	}
+
+	logger.Error("failed to reload config", "err", err.Error())
+}
+
+// WithReloadErrorHandler sets the function Watch calls when reloading the configuration fails (e.g the file no longer parses or fails validation)
+// The previously published configuration stays in use, by default the error is logged
+// It is also called with the warnings of a reload (e.g a stale url cache being used), in which case the reloaded configuration is still published
+func WithReloadErrorHandler(handler func(error)) OptionFunc {
+	return func(o *options) error {
+		o.watch.onError = handler
+		return nil
+	}
+}
+
+// WithReloadOnSIGHUP makes Watch (and WatchStore) reload the configuration when the process receives SIGHUP, e.g from systemctl reload or kill -HUP
+// Failures are passed to the WithReloadErrorHandler handler and the last good configuration stays in use
+// SIGHUP only exists on unix systems, elsewhere this logs a warning and has no effect
+func WithReloadOnSIGHUP() OptionFunc {
+	return func(o *options) error {
+		o.watch.onSIGHUP = true
+		return nil
+	}
+}
+
+// WithStaticPolicy sets what Watch does when a reload would change a field with the static modifier (e.g confy:"listen;static"), by default the reload is rejected
+func WithStaticPolicy(policy StaticPolicy) OptionFunc {
+	return func(o *options) error {
+		if policy != RejectStaticChanges && policy != KeepStaticFields {
+			return fmt.Errorf("unknown static policy %d", policy)
+		}
+
+		o.watch.staticPolicy = policy
+		return nil
+	}
+}
+
+// WithPollInterval makes Watch check the config file's modification time and size every interval instead of relying on filesystem notifications
+// Useful for network filesystems and containers where notifications are not delivered. For FromConfigURL this is how often the url is requested
+func WithPollInterval(interval time.Duration) OptionFunc {
+	return func(o *options) error {
+		if interval <= 0 {
+			return errors.New("poll interval must be positive")
+		}
+
+		o.watch.pollInterval = interval
+		return nil
+	}
+}
+
+// Watch loads the configuration the same way as Config, then watches the file used by FromConfigFile, FromConfigFileFlagPath (or Defaults) or the FromConfigURL url for changes
+// On a change the whole source pipeline is run again (file, envs and cli in the configured order) and the result is validated, only a valid configuration that differs from the last one is sent on updates
+// Reload failures are passed to the WithReloadErrorHandler handler, updates is closed once ctx is done
+// Filesystem notifications are used where available, falling back to polling (see WithPollInterval) where they are not
+// Urls are requested every 30 seconds (or the WithPollInterval interval) with If-None-Match/If-Modified-Since, or held open as blocking queries with WithURLLongPoll
+// Fields with the static modifier (e.g confy:"listen;static") cannot change on reload, see WithStaticPolicy
+// With WithReloadOnSIGHUP the configuration is also reloaded when the process receives SIGHUP, in which case a config file is not required
+// A config file that does not exist yet (and is not required, see WithConfigRequired) is watched for being created
+// A flag set given with WithFlagSet must have been registered with RegisterFlags, otherwise confy would define its flags again on every reload
+func Watch[T any](ctx context.Context, suppliedOptions ...OptionFunc) (initial T, updates <-chan T, err error) {
+	initial, _, o, err := load[T](ctx, suppliedOptions)
+	if err != nil {
+		return initial, nil, err
+	}
+
+	if o.cli.external && len(boundFlags(o.cli.commandLine)) == 0 {
+		return initial, nil, errors.New("Watch(...) requires a flag set given with WithFlagSet to be registered with RegisterFlags")
+	}
+
+	// a nil channel is never ready, so a source that is not in use never triggers a reload
+	var changes <-chan struct{}
+	switch {
+	case o.config.filePath != nil:
+		path := o.config.filePath()
+		changes, err = watchFile(ctx, path, o.watch.pollInterval)
+		if err != nil {
+			return initial, nil, fmt.Errorf("failed to watch config file %q: %w", path, err)
+		}
+	case o.config.url != nil:
+		interval := o.watch.pollInterval
+		if interval == 0 {
+			interval = defaultURLPollInterval
+		}
+
+		changes, err = o.config.url.watch(ctx, o, interval)
+		if err != nil {
+			return initial, nil, fmt.Errorf("failed to watch config url %q: %w", o.config.url.url, err)
+		}
+	}
+
+	signals := o.watch.reloadSignals()
+	if changes == nil && len(signals) == 0 {
+		return initial, nil, errors.New("Watch(...) requires the config to be loaded from a file or url, use FromConfigFile, FromConfigFileFlagPath, FromConfigURL or Defaults")
+	}
+
+	var received chan os.Signal
+	if len(signals) > 0 {
+		// registered before returning so that a signal sent as soon as Watch returns is not missed
+		received = make(chan os.Signal, 1)
+		signal.Notify(received, signals...)
+	}
+
+	output := make(chan T)
+	go func() {
+		defer close(output)
+		if received != nil {
+			defer signal.Stop(received)
+		}
+
+		current := initial
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-changes:
+				if !ok {
+					return
+				}
+				logger.Info("config source changed, reloading")
+			case sig := <-received:
+				logger.Info("received signal, reloading config", "signal", sig.String())
+			}
+
+			next, warnings, _, err := load[T](ctx, suppliedOptions)
+			if len(warnings) > 0 {
+				// e.g a stale url cache being used, or a deprecated alias
+				o.watch.reloadError(fmt.Errorf("reloaded config with warnings: %w", errors.Join(warnings...)))
+			}
+
+			if err == nil {
+				err = checkStatic(o.watch.staticPolicy, current, &next)
+			}
+
+			if err != nil {
+				o.watch.reloadError(fmt.Errorf("failed to reload config: %w", err))
+				continue
+			}
+
+			diff := Diff(current, next)
+			if len(diff) == 0 {
+				logger.Info("reloaded config is unchanged")
+				continue
+			}
+
+			for _, change := range diff {
+				logger.Info("config changed on reload", "change", change.String())
+			}
+
+			select {
+			case output <- next:
+				current = next
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return initial, output, nil
+}
+
+// checkStatic compares the fields with the static modifier between current and next, depending on policy changes are either an error or reverted in next
+func checkStatic[T any](policy StaticPolicy, current T, next *T) error {
+	var (
+		errs    []error
+		checked []string
+	)
+	for _, entry := range diffValues(reflect.ValueOf(&current).Elem(), reflect.ValueOf(next).Elem()) {
+		path := strings.Join(entry.static, ".")
+		if entry.static == nil || slices.Contains(checked, path) {
+			continue
+		}
+		checked = append(checked, path)
+
+		if policy == KeepStaticFields {
+			logger.Warn("static field changed on reload, keeping the current value until restart", "path", path)
+			getConfyField(reflect.ValueOf(next).Elem(), entry.static).Set(getConfyField(reflect.ValueOf(&current).Elem(), entry.static))
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("%s is static and cannot change without a restart", path))
+	}
+
+	return errors.Join(errs...)
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// watchFile signals on the returned channel whenever the file at path may have changed, many changes may be coalesced into one signal
+// The channel is closed once ctx is done
+func watchFile(ctx context.Context, path string, pollInterval time.Duration) (<-chan struct{}, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// a missing file is not an error as the config file is optional unless WithConfigRequired is used, it is watched for being created
+	last, err := statFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	if pollInterval == 0 {
+		watcher, err := fsnotify.NewWatcher()
+		if err == nil {
+			// the directory is watched rather than the file, as editors and kubernetes config maps replace the file instead of writing to it
+			if err = watcher.Add(filepath.Dir(path)); err != nil {
+				watcher.Close()
+			}
+		}
+
+		if err == nil {
+			go notifyChanges(ctx, watcher, path, last, changes, notify)
+			return changes, nil
+		}
+
+		logger.Warn("filesystem notifications unavailable, polling config file instead", "path", path, "err", err.Error())
+		pollInterval = defaultPollInterval
+	}
+
+	go pollChanges(ctx, pollInterval, path, last, changes, notify)
+	return changes, nil
+}
+
+func notifyChanges(ctx context.Context, watcher *fsnotify.Watcher, path string, last fileState, changes chan struct{}, notify func()) {
+	defer close(changes)
+	defer watcher.Close()
+
+	var (
+		debounce <-chan time.Time
+		// touched is set when an event named the file itself, rather than something else within its directory (e.g a symlink being swapped)
+		touched bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			if filepath.Clean(event.Name) == path {
+				touched = true
+			}
+			debounce = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			logger.Warn("config file watcher issued warning", "path", path, "err", err.Error())
+		case <-debounce:
+			debounce = nil
+
+			current, err := statFile(path)
+			if err != nil {
+				// the file is likely being replaced, the next event will bring it back
+				logger.Info("config file could not be read after change", "path", path, "err", err.Error())
+				continue
+			}
+
+			if touched || current != last {
+				touched = false
+				last = current
+				notify()
+			}
+		}
+	}
+}
+
+func pollChanges(ctx context.Context, interval time.Duration, path string, last fileState, changes chan struct{}, notify func()) {
+	defer close(changes)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current, err := statFile(path)
+			if err != nil {
+				continue
+			}
+
+			if current != last {
+				last = current
+				notify()
+			}
+		}
+	}
+}
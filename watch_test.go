@@ -0,0 +1,301 @@
+package confy
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type watchStruct struct {
+	Level string `confy_oneof:"debug,info,warn"`
+	Port  int
+}
+
+func (w watchStruct) Validate() error {
+	if w.Port > 65535 {
+		return errors.New("port out of range")
+	}
+	return nil
+}
+
+func receiveUpdate(t *testing.T, updates <-chan watchStruct) watchStruct {
+	t.Helper()
+
+	select {
+	case update, ok := <-updates:
+		if !ok {
+			t.Fatal("updates closed unexpectedly")
+		}
+		return update
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for config update")
+	}
+
+	return watchStruct{}
+}
+
+func TestWatch(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"Level": "info", "Port": 80}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	initial, updates, err := Watch[watchStruct](ctx, FromConfigFile(path, Auto))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if initial.Level != "info" || initial.Port != 80 {
+		t.Fatalf("unexpected initial config: %+v", initial)
+	}
+
+	// replace the file the way editors do, by renaming over it
+	replacement := filepath.Join(filepath.Dir(path), "config.json.tmp")
+	if err := os.WriteFile(replacement, []byte(`{"Level": "debug", "Port": 8080}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Rename(replacement, path); err != nil {
+		t.Fatal(err)
+	}
+
+	update := receiveUpdate(t, updates)
+	if update.Level != "debug" || update.Port != 8080 {
+		t.Fatalf("unexpected update: %+v", update)
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Fatal("expected updates to be closed once the context was cancelled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("updates was not closed after cancel")
+	}
+}
+
+func TestWatchPollingRejectsInvalid(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"Level": "info", "Port": 80}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloadErrors := make(chan error, 10)
+	_, updates, err := Watch[watchStruct](ctx,
+		FromConfigFile(path, Auto),
+		WithPollInterval(10*time.Millisecond),
+		WithReloadErrorHandler(func(err error) {
+			reloadErrors <- err
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, invalid := range []struct {
+		content  string
+		expected string
+	}{
+		{`{"Level": "trace", "Port": 80}`, "must be one of"},
+		{`{"Level": "info", "Port":`, "failed to reload config"},
+		{`{"Level": "info", "Port": 700000}`, "port out of range"},
+	} {
+		if err := os.WriteFile(path, []byte(invalid.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-reloadErrors:
+			if !strings.Contains(err.Error(), invalid.expected) {
+				t.Fatalf("expected reload error containing %q, got: %s", invalid.expected, err)
+			}
+		case update := <-updates:
+			t.Fatalf("invalid config was published: %+v", update)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for reload error")
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(`{"Level": "warn", "Port": 443}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	update := receiveUpdate(t, updates)
+	if update.Level != "warn" || update.Port != 443 {
+		t.Fatalf("unexpected update: %+v", update)
+	}
+}
+
+func TestWatchRequiresFile(t *testing.T) {
+
+	_, _, err := Watch[watchStruct](context.Background(), FromConfigBytes([]byte(`{"Level": "info"}`), Json))
+	if err == nil {
+		t.Fatal("expected Watch to fail without a config file")
+	}
+}
+
+func TestWatchFlagSet(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"Level": "info", "Port": 80}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := []OptionFunc{FromConfigFile(path, Auto), FromCli(CLIDelimiter), WithArgs([]string{"-Level", "debug"})}
+
+	_, _, err := Watch[watchStruct](ctx, append(options, WithFlagSet(flag.NewFlagSet("app", flag.ContinueOnError)))...)
+	if err == nil || !strings.Contains(err.Error(), "RegisterFlags") {
+		t.Fatalf("expected a flag set not built with RegisterFlags to be rejected, got: %v", err)
+	}
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	if err := RegisterFlags[watchStruct](fs, options...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Parse([]string{"-Level", "debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	initial, _, err := Watch[watchStruct](ctx, append(options, WithFlagSet(fs))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if initial.Level != "debug" || initial.Port != 80 {
+		t.Fatalf("unexpected initial config: %+v", initial)
+	}
+}
+
+type staticStruct struct {
+	Listen  string `confy:"listen;static"`
+	DataDir string `confy:"data_dir;static"`
+	Level   string
+}
+
+func TestCheckStaticNested(t *testing.T) {
+
+	type nestedStatic struct {
+		Server struct {
+			Peers []string `confy:"peers;static"`
+			Name  string   `confy:"name"`
+		} `confy:"server"`
+	}
+
+	var current nestedStatic
+	current.Server.Peers = []string{"a", "b"}
+
+	next := current
+	next.Server.Peers = []string{"c", "d", "e"}
+	next.Server.Name = "renamed"
+
+	err := checkStatic(RejectStaticChanges, current, &next)
+	if err == nil || err.Error() != "server.peers is static and cannot change without a restart" {
+		t.Fatalf("expected a single error naming the static field, got: %v", err)
+	}
+
+	if err := checkStatic(KeepStaticFields, current, &next); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(next.Server.Peers) != 2 || next.Server.Name != "renamed" {
+		t.Fatalf("expected the static field to be kept and the rest applied: %+v", next)
+	}
+}
+
+func TestWatchStaticFields(t *testing.T) {
+
+	for _, policy := range []StaticPolicy{RejectStaticChanges, KeepStaticFields} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"listen": ":80", "data_dir": "/var/lib/app", "Level": "info"}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		reloadErrors := make(chan error, 10)
+		_, updates, err := Watch[staticStruct](ctx,
+			FromConfigFile(path, Auto),
+			WithPollInterval(10*time.Millisecond),
+			WithStaticPolicy(policy),
+			WithReloadErrorHandler(func(err error) {
+				reloadErrors <- err
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(`{"listen": ":8080", "data_dir": "/tmp", "Level": "debug"}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-reloadErrors:
+			if policy != RejectStaticChanges {
+				t.Fatalf("unexpected reload error: %s", err)
+			}
+
+			for _, expected := range []string{"listen is static", "data_dir is static"} {
+				if !strings.Contains(err.Error(), expected) {
+					t.Fatalf("expected %q in reload error: %s", expected, err)
+				}
+			}
+		case update := <-updates:
+			if policy != KeepStaticFields {
+				t.Fatalf("config changing static fields was published: %+v", update)
+			}
+
+			if update.Listen != ":80" || update.DataDir != "/var/lib/app" || update.Level != "debug" {
+				t.Fatalf("expected static fields to be kept and the rest applied: %+v", update)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for reload")
+		}
+	}
+}
+
+func TestWatchMissingFile(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	initial, updates, err := Watch[watchStruct](ctx, FromConfigFile(path, Auto), FromEnvs(ENVDelimiter), WithEnviron(map[string]string{"Port": "80"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if initial.Port != 80 {
+		t.Fatalf("unexpected initial config: %+v", initial)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"Level": "debug"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	update := receiveUpdate(t, updates)
+	if update.Level != "debug" || update.Port != 80 {
+		t.Fatalf("expected the created file to be loaded, got: %+v", update)
+	}
+}